@@ -3,7 +3,6 @@ package akahu
 import (
 	"context"
 	"net/http"
-	"path"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -14,12 +13,12 @@ const accountsPath = "accounts"
 type AccountsService service
 
 type AccountResponse struct {
-	ID          string `json:"_id"`
-	Credentials string `json:"_credentials"`
+	ID          AccountID     `json:"_id"`
+	Credentials CredentialsID `json:"_credentials"`
 	Connection  struct {
-		Name string `json:"name"`
-		Logo string `json:"logo"`
-		Id   string `json:"_id"`
+		Name string       `json:"name"`
+		Logo string       `json:"logo"`
+		Id   ConnectionID `json:"_id"`
 	} `json:"connection"`
 	Name   string `json:"name"`
 	Status string `json:"status"`
@@ -58,7 +57,7 @@ func (s *AccountsService) List(ctx context.Context, userAccessToken string) ([]A
 	}
 
 	var accounts collectionResponse[AccountResponse]
-	res, err := s.client.do(ctx, r, &accounts)
+	res, err := s.client.do(ctx, "akahu.Accounts.List", r, &accounts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -69,14 +68,18 @@ func (s *AccountsService) List(ctx context.Context, userAccessToken string) ([]A
 // Get an individual account that the user has connected to your application.
 //
 // Akahu docs: https://developers.akahu.nz/reference/get_accounts-id
-func (s *AccountsService) Get(ctx context.Context, userAccessToken, ID string) (*AccountResponse, *APIResponse, error) {
-	r, err := s.client.newRequest(http.MethodGet, path.Join(accountsPath, ID), nil, withTokenRequestConfig(userAccessToken))
+func (s *AccountsService) Get(ctx context.Context, userAccessToken string, ID AccountID) (*AccountResponse, *APIResponse, error) {
+	if err := checkID("accountID", ID); err != nil {
+		return nil, nil, err
+	}
+
+	r, err := s.client.newRequest(http.MethodGet, joinPath(accountsPath, string(ID)), nil, withTokenRequestConfig(userAccessToken))
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var accounts itemResponse[AccountResponse]
-	res, err := s.client.do(ctx, r, &accounts)
+	res, err := s.client.do(ctx, "akahu.Accounts.Get", r, &accounts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -87,14 +90,18 @@ func (s *AccountsService) Get(ctx context.Context, userAccessToken, ID string) (
 // Revoke your application's access to one of the user's connected accounts and its associated data, including transactions.
 //
 // Akahu docs: https://developers.akahu.nz/reference/delete_accounts-id
-func (s *AccountsService) Revoke(ctx context.Context, userAccessToken, ID string) (bool, *APIResponse, error) {
-	r, err := s.client.newRequest(http.MethodDelete, path.Join(accountsPath, ID), nil, withTokenRequestConfig(userAccessToken))
+func (s *AccountsService) Revoke(ctx context.Context, userAccessToken string, ID AccountID) (bool, *APIResponse, error) {
+	if err := checkID("accountID", ID); err != nil {
+		return false, nil, err
+	}
+
+	r, err := s.client.newRequest(http.MethodDelete, joinPath(accountsPath, string(ID)), nil, withTokenRequestConfig(userAccessToken))
 	if err != nil {
 		return false, nil, err
 	}
 
 	var successResponse successResponse
-	res, err := s.client.do(ctx, r, &successResponse)
+	res, err := s.client.do(ctx, "akahu.Accounts.Revoke", r, &successResponse)
 	if err != nil {
 		return false, nil, err
 	}
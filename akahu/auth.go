@@ -2,8 +2,11 @@ package akahu
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 const (
@@ -13,15 +16,23 @@ const (
 
 type AuthService service
 
-type grantType string
+// GrantType is the OAuth grant used to obtain a user access token.
+type GrantType string
 
 const (
-	authCode grantType = "authorization_code"
+	GrantTypeAuthorizationCode GrantType = "authorization_code"
 )
 
+// TokenRequest is a request for a user access token, using one of the supported grant types.
+type TokenRequest struct {
+	GrantType GrantType
+	// Code is required by GrantTypeAuthorizationCode.
+	Code string
+}
+
 type exchangeRequest struct {
-	GrantType    grantType `json:"grant_type"`
-	Code         string    `json:"code"`
+	GrantType    GrantType `json:"grant_type"`
+	Code         string    `json:"code,omitempty"`
 	RedirectURI  string    `json:"redirect_uri"`
 	ClientID     string    `json:"client_id"`
 	ClientSecret string    `json:"client_secret"`
@@ -31,23 +42,98 @@ type ExchangeResponse struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
 	Scope       string `json:"scope"`
+	// ExpiresIn is the lifetime of the access token in seconds, if Akahu has given it one.
+	ExpiresIn *int64 `json:"expires_in,omitempty"`
+	// ExpiresAt is calculated from ExpiresIn when the response is received.
+	ExpiresAt *time.Time `json:"-"`
+}
+
+// Scopes splits Scope into the individual scopes that were granted.
+func (r *ExchangeResponse) Scopes() []Scope {
+	fields := strings.Fields(r.Scope)
+	scopes := make([]Scope, len(fields))
+	for i, field := range fields {
+		scopes[i] = Scope(field)
+	}
+
+	return scopes
+}
+
+// TokenIntrospection describes whether a user access token can still be used.
+type TokenIntrospection struct {
+	Active bool
+	// User is the user the token belongs to, if the token is active.
+	User *MeResponse
+}
+
+// ResponseType is the OAuth response type requested from the Akahu authorization page.
+type ResponseType string
+
+const (
+	ResponseTypeCode ResponseType = "code"
+)
+
+// Scope is an OAuth scope requested from the Akahu authorization page.
+type Scope string
+
+const (
+	// ScopeEnduringConsent requests ongoing access to the user's data. This is the default.
+	ScopeEnduringConsent Scope = "ENDURING_CONSENT"
+	// ScopeOneOff requests one-off access to the user's data, e.g. for identity verification.
+	ScopeOneOff Scope = "ONEOFF"
+
+	ScopeIdentity      Scope = "IDENTITY"
+	ScopeIdentityBasic Scope = "IDENTITY_BASIC"
+	ScopeAccounts      Scope = "ACCOUNTS"
+	ScopeTransactions  Scope = "TRANSACTIONS"
+	ScopePayments      Scope = "PAYMENTS"
+	ScopeTransfers     Scope = "TRANSFERS"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeEnduringConsent: true,
+	ScopeOneOff:          true,
+	ScopeIdentity:        true,
+	ScopeIdentityBasic:   true,
+	ScopeAccounts:        true,
+	ScopeTransactions:    true,
+	ScopePayments:        true,
+	ScopeTransfers:       true,
 }
 
 type AuthorizationURLOptions struct {
-	ResponseType *string
+	// ResponseType defaults to ResponseTypeCode.
+	ResponseType ResponseType
 	Email        *string
 	Connection   *string
-	Scope        *string
-	State        *string
+	// Scopes defaults to ScopeEnduringConsent. Otherwise exactly one of ScopeEnduringConsent or ScopeOneOff must be given.
+	Scopes []Scope
+	State  *string
 }
 
 // Exchange Use this endpoint to exchange an Authorization Code for a User Access Token, which can be used to access the rest of this API.
 //
 // Akahu docs: https://developers.akahu.nz/reference/post_token
 func (s *AuthService) Exchange(ctx context.Context, code string) (*ExchangeResponse, *APIResponse, error) {
+	return s.Token(ctx, TokenRequest{GrantType: GrantTypeAuthorizationCode, Code: code})
+}
+
+// Token requests a User Access Token using any of the supported grant types.
+//
+// Akahu docs: https://developers.akahu.nz/reference/post_token
+func (s *AuthService) Token(ctx context.Context, tokenRequest TokenRequest) (*ExchangeResponse, *APIResponse, error) {
+	switch tokenRequest.GrantType {
+	case GrantTypeAuthorizationCode:
+		if err := checkNotEmpty("code", tokenRequest.Code); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, &ValidationError{Field: "grantType", Reason: fmt.Sprintf("unsupported grant type %q", tokenRequest.GrantType)}
+	}
+
 	body := exchangeRequest{
-		GrantType:    authCode,
-		Code:         code,
+		GrantType:    tokenRequest.GrantType,
+		Code:         tokenRequest.Code,
 		RedirectURI:  s.client.RedirectURI.String(),
 		ClientID:     s.client.AppIDToken,
 		ClientSecret: s.client.AppSecret,
@@ -59,7 +145,7 @@ func (s *AuthService) Exchange(ctx context.Context, code string) (*ExchangeRespo
 	}
 
 	var exchangeResponse ExchangeResponse
-	res, err := s.client.do(ctx, r, &exchangeResponse)
+	res, err := s.client.do(ctx, "akahu.Auth.Exchange", r, &exchangeResponse)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -68,10 +154,35 @@ func (s *AuthService) Exchange(ctx context.Context, code string) (*ExchangeRespo
 		return nil, res, nil
 	}
 
+	if exchangeResponse.ExpiresIn != nil {
+		expiresAt := time.Now().Add(time.Duration(*exchangeResponse.ExpiresIn) * time.Second)
+		exchangeResponse.ExpiresAt = &expiresAt
+	}
+
 	return &exchangeResponse, res, nil
 }
 
+// Introspect checks whether a User Access Token is still valid, by fetching the user it belongs to with MeService.Get.
+// A token that Akahu rejects as unauthorized is reported as inactive rather than as an error.
+// Nil is returned along with the APIResponse if Akahu fails for any other reason.
+func (s *AuthService) Introspect(ctx context.Context, userAccessToken string) (*TokenIntrospection, *APIResponse, error) {
+	me, res, err := s.client.Me.Get(ctx, userAccessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case res.Success:
+		return &TokenIntrospection{Active: true, User: me}, res, nil
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return &TokenIntrospection{Active: false}, res, nil
+	}
+
+	return nil, res, nil
+}
+
 // RevokeToken Revokes the User Access Token that is included in the Authorization header of the request.
+// The token is also removed from Client.Tokens.
 //
 // Akahu docs: https://developers.akahu.nz/reference/delete_token
 func (s *AuthService) RevokeToken(ctx context.Context, userAccessToken string) (bool, *APIResponse, error) {
@@ -81,35 +192,50 @@ func (s *AuthService) RevokeToken(ctx context.Context, userAccessToken string) (
 	}
 
 	var successResponse successResponse
-	res, err := s.client.do(ctx, r, &successResponse)
+	res, err := s.client.do(ctx, "akahu.Auth.RevokeToken", r, &successResponse)
 	if err != nil {
 		return false, nil, err
 	}
 
+	if successResponse.Success && s.client.Tokens != nil {
+		if err := s.client.Tokens.DeleteToken(ctx, userAccessToken); err != nil {
+			return true, res, err
+		}
+	}
+
 	return successResponse.Success, res, nil
 }
 
 // BuildAuthorizationURL Builds the URL that redirects the user to the Akahu authorization page.
 // This is the first step in the authorization flow.
+// An error is returned if the options contain an unknown response type, or an invalid combination of scopes.
 //
 // See the Authorizing with OAuth 2.0 guide for more information: https://developers.akahu.nz/docs/authorizing-with-oauth2.
-func (s *AuthService) BuildAuthorizationURL(options AuthorizationURLOptions) string {
-	var responseType string
-	if options.ResponseType == nil {
-		responseType = "code"
-	} else {
-		responseType = *options.ResponseType
+func (s *AuthService) BuildAuthorizationURL(options AuthorizationURLOptions) (string, error) {
+	responseType := options.ResponseType
+	if responseType == "" {
+		responseType = ResponseTypeCode
+	}
+	if responseType != ResponseTypeCode {
+		return "", &ValidationError{Field: "responseType", Reason: fmt.Sprintf("unsupported response type %q", responseType)}
+	}
+
+	scopes := options.Scopes
+	if len(scopes) == 0 {
+		scopes = []Scope{ScopeEnduringConsent}
+	}
+	if err := validateScopes(scopes); err != nil {
+		return "", err
 	}
-	var scope string
-	if options.Scope == nil {
-		scope = "ENDURING_CONSENT"
-	} else {
-		scope = *options.Scope
+
+	scopeNames := make([]string, len(scopes))
+	for i, scope := range scopes {
+		scopeNames[i] = string(scope)
 	}
 
 	params := url.Values{}
-	params.Add("response_type", responseType)
-	params.Add("scope", scope)
+	params.Add("response_type", string(responseType))
+	params.Add("scope", strings.Join(scopeNames, " "))
 
 	params.Add("client_id", s.client.AppIDToken)
 	params.Add("redirect_uri", s.client.RedirectURI.String())
@@ -127,5 +253,29 @@ func (s *AuthService) BuildAuthorizationURL(options AuthorizationURLOptions) str
 	authURL, _ := url.Parse(authURLBasePath)
 	authURL.RawQuery = params.Encode()
 
-	return authURL.String()
+	return authURL.String(), nil
+}
+
+func validateScopes(scopes []Scope) error {
+	seen := map[Scope]bool{}
+	for _, scope := range scopes {
+		if !knownScopes[scope] {
+			return &ValidationError{Field: "scopes", Reason: fmt.Sprintf("unknown scope %q", scope)}
+		}
+		if seen[scope] {
+			return &ValidationError{Field: "scopes", Reason: fmt.Sprintf("duplicate scope %q", scope)}
+		}
+		seen[scope] = true
+	}
+
+	switch {
+	case seen[ScopeEnduringConsent] && seen[ScopeOneOff]:
+		return &ValidationError{Field: "scopes", Reason: fmt.Sprintf("%s and %s cannot be combined", ScopeEnduringConsent, ScopeOneOff)}
+	case !seen[ScopeEnduringConsent] && !seen[ScopeOneOff]:
+		return &ValidationError{Field: "scopes", Reason: fmt.Sprintf("must include %s or %s", ScopeEnduringConsent, ScopeOneOff)}
+	case seen[ScopeOneOff] && (seen[ScopePayments] || seen[ScopeTransfers]):
+		return &ValidationError{Field: "scopes", Reason: fmt.Sprintf("%s access cannot be used for payments or transfers", ScopeOneOff)}
+	}
+
+	return nil
 }
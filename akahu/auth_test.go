@@ -2,8 +2,12 @@ package akahu
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"testing"
+	"time"
 )
 
 const exchangeJson = "{ \"success\": true, \"access_token\": \"user_token_1111111111111111111111111\", \"token_type\": \"bearer\", \"scope\": \"IDENTITY_BASIC ACCOUNTS TRANSACTIONS\" }"
@@ -12,14 +16,13 @@ func TestAuthService_BuildAuthorizationURL(t *testing.T) {
 	client := NewClient(nil, "app_token_123", "appsecret123", "https://example.com/auth/akahu")
 	email := "test_user@gmail.com"
 	connection := "conn_1234"
-	responseType := "codetest"
 	state := "1234567890"
-	scope := "ENDURING_CONSENT_TEST"
 
 	tests := []struct {
-		name     string
-		opts     AuthorizationURLOptions
-		expected string
+		name        string
+		opts        AuthorizationURLOptions
+		expected    string
+		expectedErr bool
 	}{
 		{
 			name:     "with all defaults configurations",
@@ -37,19 +40,58 @@ func TestAuthService_BuildAuthorizationURL(t *testing.T) {
 		{
 			name: "with all options configured",
 			opts: AuthorizationURLOptions{
-				ResponseType: &responseType,
+				ResponseType: ResponseTypeCode,
 				Email:        &email,
 				Connection:   &connection,
-				Scope:        &scope,
+				Scopes:       []Scope{ScopeOneOff, ScopeIdentity, ScopeAccounts},
 				State:        &state,
 			},
-			expected: "https://oauth.akahu.io/?client_id=app_token_123&connection=conn_1234&email=test_user%40gmail.com&redirect_uri=https%3A%2F%2Fexample.com%2Fauth%2Fakahu&response_type=codetest&scope=ENDURING_CONSENT_TEST&state=1234567890",
+			expected: "https://oauth.akahu.io/?client_id=app_token_123&connection=conn_1234&email=test_user%40gmail.com&redirect_uri=https%3A%2F%2Fexample.com%2Fauth%2Fakahu&response_type=code&scope=ONEOFF+IDENTITY+ACCOUNTS&state=1234567890",
+		},
+		{
+			name:        "with unknown response type",
+			opts:        AuthorizationURLOptions{ResponseType: "token"},
+			expectedErr: true,
+		},
+		{
+			name:        "with unknown scope",
+			opts:        AuthorizationURLOptions{Scopes: []Scope{ScopeEnduringConsent, "ENDURING_CONSENT_TEST"}},
+			expectedErr: true,
+		},
+		{
+			name:        "with duplicate scope",
+			opts:        AuthorizationURLOptions{Scopes: []Scope{ScopeEnduringConsent, ScopeEnduringConsent}},
+			expectedErr: true,
+		},
+		{
+			name:        "with both consent scopes",
+			opts:        AuthorizationURLOptions{Scopes: []Scope{ScopeEnduringConsent, ScopeOneOff}},
+			expectedErr: true,
+		},
+		{
+			name:        "with no consent scope",
+			opts:        AuthorizationURLOptions{Scopes: []Scope{ScopeAccounts}},
+			expectedErr: true,
+		},
+		{
+			name:        "with one-off payments",
+			opts:        AuthorizationURLOptions{Scopes: []Scope{ScopeOneOff, ScopePayments}},
+			expectedErr: true,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			if actual := client.Auth.BuildAuthorizationURL(test.opts); actual != test.expected {
+			actual, err := client.Auth.BuildAuthorizationURL(test.opts)
+			if gotErr := err != nil; gotErr != test.expectedErr {
+				t.Fatalf("expected error %t, actual %v", test.expectedErr, err)
+			}
+			var validationErr *ValidationError
+			if test.expectedErr && !errors.As(err, &validationErr) {
+				t.Fatalf("expected *ValidationError, actual %v", err)
+			}
+
+			if actual != test.expected {
 				t.Errorf("expected %v, actual %v", test.expected, actual)
 			}
 		})
@@ -86,11 +128,122 @@ func TestAuthService_Exchange(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			hook := &recordingHook{name: "hook", calls: &calls}
 			client := setupClient(t, test.jsonResponse, http.MethodPost, test.statusCode)
+			client.Hooks = []RequestHook{hook}
 
 			actual, res, err := client.Auth.Exchange(context.TODO(), "code")
 			testClientResponse(t, test.expected, actual, err)
 			testClientAPIResponse(t, test.expectedAPIResponse, res, err)
+
+			if operation := hook.infos[0].Operation; operation != "akahu.Auth.Exchange" {
+				t.Fatalf("expected operation %s, actual %s", "akahu.Auth.Exchange", operation)
+			}
+		})
+	}
+}
+
+func TestAuthService_Token(t *testing.T) {
+	tests := []struct {
+		name         string
+		request      TokenRequest
+		jsonResponse string
+		expectedBody string
+		// expectedErrField is the Field of the *ValidationError expected, if any.
+		expectedErrField string
+	}{
+		{
+			name:         "with authorization code grant and expiry",
+			request:      TokenRequest{GrantType: GrantTypeAuthorizationCode, Code: "code_1"},
+			jsonResponse: "{ \"success\": true, \"access_token\": \"user_token_1\", \"token_type\": \"bearer\", \"scope\": \"ENDURING_CONSENT\", \"expires_in\": 3600 }",
+			expectedBody: "{\"grant_type\":\"authorization_code\",\"code\":\"code_1\",\"redirect_uri\":\"\",\"client_id\":\"app_token_123\",\"client_secret\":\"appSecret123\"}\n",
+		},
+		{
+			name:             "with missing code",
+			request:          TokenRequest{GrantType: GrantTypeAuthorizationCode},
+			expectedErrField: "code",
+		},
+		{
+			name:             "with unsupported grant type",
+			request:          TokenRequest{GrantType: "refresh_token"},
+			expectedErrField: "grantType",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setupClient(t, test.jsonResponse, http.MethodPost, http.StatusOK, func(r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != test.expectedBody {
+					t.Fatalf("expected request body %s, actual %s", test.expectedBody, body)
+				}
+			})
+
+			actual, _, err := client.Auth.Token(context.TODO(), test.request)
+			if test.expectedErrField != "" {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Field != test.expectedErrField {
+					t.Fatalf("expected *ValidationError for %s, actual %v", test.expectedErrField, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Token returned err %v", err)
+			}
+
+			if actual.ExpiresIn != nil {
+				if actual.ExpiresAt == nil || time.Until(*actual.ExpiresAt) <= 59*time.Minute {
+					t.Fatalf("expected ExpiresAt an hour from now, actual %v", actual.ExpiresAt)
+				}
+			} else if actual.ExpiresAt != nil {
+				t.Fatalf("expected no ExpiresAt, actual %v", actual.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestAuthService_Introspect(t *testing.T) {
+	tests := []struct {
+		name         string
+		jsonResponse string
+		statusCode   int
+		expected     *TokenIntrospection
+	}{
+		{
+			name:         "with active token",
+			jsonResponse: fmt.Sprintf(itemResponseJson, "{ \"_id\": \"user_1111111111111111111111111\", \"email\": \"test_user@gmail.com\" }"),
+			statusCode:   http.StatusOK,
+			expected: &TokenIntrospection{
+				Active: true,
+				User: &MeResponse{
+					Id:    "user_1111111111111111111111111",
+					Email: "test_user@gmail.com",
+				},
+			},
+		},
+		{
+			name:         "with revoked token",
+			jsonResponse: errorResponseJsonWithMessage,
+			statusCode:   http.StatusUnauthorized,
+			expected:     &TokenIntrospection{Active: false},
+		},
+		{
+			name:         "with server error",
+			jsonResponse: errorResponseJsonWithMessage,
+			statusCode:   http.StatusInternalServerError,
+			expected:     nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setupClient(t, test.jsonResponse, http.MethodGet, test.statusCode, func(r *http.Request) {
+				testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
+			})
+
+			actual, _, err := client.Auth.Introspect(context.TODO(), "user_token_1")
+			testClientResponse(t, test.expected, actual, err)
 		})
 	}
 }
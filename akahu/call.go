@@ -0,0 +1,101 @@
+package akahu
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// AuthMode is how a request made with Client.Do is authenticated.
+type AuthMode struct {
+	userAccessToken string
+	user            bool
+	basic           bool
+}
+
+var (
+	// NoAuth sends no credentials, as for the token endpoint where the app's credentials are in the body.
+	NoAuth = AuthMode{}
+	// AppAuth authenticates as your app with its ID and secret, as for app scoped endpoints such as connections.
+	AppAuth = AuthMode{basic: true}
+)
+
+// UserAuth authenticates as a user with their access token, as for user scoped endpoints such as accounts.
+func UserAuth(userAccessToken string) AuthMode {
+	return AuthMode{userAccessToken: userAccessToken, user: true}
+}
+
+func (a AuthMode) requestConfigs() []requestConfig {
+	switch {
+	case a.basic:
+		return []requestConfig{withBasicAuthRequestConfig()}
+	case a.user:
+		return []requestConfig{withTokenRequestConfig(a.userAccessToken)}
+	}
+
+	return nil
+}
+
+// Do calls an endpoint of the Akahu API that the SDK doesn't cover yet. The request goes through the client's
+// middleware and hooks like any other call.
+//
+// urlPath is relative to Client.BaseURL, e.g. "accounts", and may include a query. Escape IDs in the path with
+// url.PathEscape. body, if not nil, is sent as JSON, and the whole JSON response, including its "success" field, is
+// decoded into out. Use Call and CallList to decode the item or items of the response instead.
+//
+// As with the service methods, an unsuccessful response from Akahu is returned as an APIResponse without an error.
+func (c *Client) Do(ctx context.Context, method, urlPath string, body, out interface{}, auth AuthMode) (*APIResponse, error) {
+	u, err := url.Parse(urlPath)
+	if err != nil {
+		return nil, &ValidationError{Field: "urlPath", Reason: err.Error(), Err: err}
+	}
+	if u.Scheme != "" || u.Host != "" {
+		// Credentials must only be sent to BaseURL.
+		return nil, &ValidationError{Field: "urlPath", Reason: "must be relative to BaseURL"}
+	}
+	if auth.user {
+		if err := checkNotEmpty("userAccessToken", auth.userAccessToken); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := c.newRequest(method, strings.TrimLeft(urlPath, "/"), body, auth.requestConfigs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	if out == nil {
+		var discard successResponse
+		out = &discard
+	}
+
+	return c.do(ctx, "akahu.Do", r, out)
+}
+
+// Call calls an endpoint with Client.Do, returning the "item" of the response.
+func Call[T any](ctx context.Context, c *Client, method, urlPath string, body interface{}, auth AuthMode) (*T, *APIResponse, error) {
+	var item itemResponse[T]
+	res, err := c.Do(ctx, method, urlPath, body, &item, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return item.Item, res, nil
+}
+
+// CallList calls an endpoint with Client.Do, returning the "items" of the response. For paginated endpoints,
+// APIResponse.NextCursor is set to the cursor of the next page.
+func CallList[T any](ctx context.Context, c *Client, method, urlPath string, body interface{}, auth AuthMode) ([]T, *APIResponse, error) {
+	var items collectionResponse[T]
+	res, err := c.Do(ctx, method, urlPath, body, &items, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return items.Items, res, nil
+}
+
+// Do calls Client.Do with the user's access token.
+func (u *UserClient) Do(ctx context.Context, method, urlPath string, body, out interface{}) (*APIResponse, error) {
+	return u.client.Do(ctx, method, urlPath, body, out, UserAuth(u.userAccessToken))
+}
@@ -0,0 +1,103 @@
+package akahu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type testBalance struct {
+	Account AccountID `json:"_account"`
+	Current string    `json:"current"`
+}
+
+func TestCall(t *testing.T) {
+	client := setupClient(t, fmt.Sprintf(itemResponseJson, `{ "_account": "acc_1", "current": "10.50" }`), http.MethodGet, http.StatusOK, func(r *http.Request) {
+		testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
+		if r.URL.Path != "/v1/accounts/acc_1/balance" || r.URL.RawQuery != "refresh=true" {
+			t.Fatalf("unexpected request URL %s", r.URL)
+		}
+	})
+
+	actual, res, err := Call[testBalance](context.TODO(), client, http.MethodGet, "accounts/acc_1/balance?refresh=true", nil, UserAuth("user_token_1"))
+	testClientResponse(t, &testBalance{Account: "acc_1", Current: "10.50"}, actual, err)
+	testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+}
+
+func TestCallList(t *testing.T) {
+	body := `{ "success": true, "items": [{ "_id": "conn_1", "name": "Bank" }], "cursor": { "next": "page_2" } }`
+	client := setupClient(t, body, http.MethodGet, http.StatusOK, func(r *http.Request) {
+		testBasicRequestHeaders(t, r)
+	})
+
+	actual, res, err := CallList[ConnectionResponse](context.TODO(), client, http.MethodGet, "/connections", nil, AppAuth)
+	testClientResponse(t, []ConnectionResponse{{Id: "conn_1", Name: "Bank"}}, actual, err)
+	if res.NextCursor != "page_2" {
+		t.Fatalf("expected next cursor page_2, actual %q", res.NextCursor)
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	tests := []struct {
+		name                string
+		jsonResponse        string
+		statusCode          int
+		auth                AuthMode
+		expectedAPIResponse *APIResponse
+	}{
+		{
+			name:                "with no auth",
+			jsonResponse:        `{ "success": true, "access_token": "user_token_1" }`,
+			statusCode:          http.StatusOK,
+			auth:                NoAuth,
+			expectedAPIResponse: expectedSuccessAPIResponse,
+		},
+		{
+			name:                "with error response",
+			jsonResponse:        errorResponseJsonWithMessage,
+			statusCode:          http.StatusBadRequest,
+			auth:                NoAuth,
+			expectedAPIResponse: expectedErrorAPIResponse,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setupClient(t, test.jsonResponse, http.MethodPost, test.statusCode, func(r *http.Request) {
+				if auth := r.Header.Get("Authorization"); auth != "" {
+					t.Fatalf("expected no Authorization header, actual %s", auth)
+				}
+			})
+
+			var out map[string]interface{}
+			res, err := client.Do(context.TODO(), http.MethodPost, "token", map[string]string{"grant_type": "example"}, &out, test.auth)
+			testClientAPIResponse(t, test.expectedAPIResponse, res, err)
+		})
+	}
+}
+
+func TestClient_Do_Validation(t *testing.T) {
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("expected no request to be sent, actual %s %s", req.Method, req.URL)
+		return nil, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+	for _, test := range []struct {
+		urlPath string
+		auth    AuthMode
+	}{
+		{urlPath: "https://example.com/steal", auth: AppAuth},
+		{urlPath: "//example.com/steal", auth: AppAuth},
+		{urlPath: "accounts", auth: UserAuth("")},
+	} {
+		_, err := client.Do(context.TODO(), http.MethodGet, test.urlPath, nil, nil, test.auth)
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected *ValidationError for %s, actual %v", test.urlPath, err)
+		}
+	}
+}
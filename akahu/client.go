@@ -5,16 +5,23 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 const (
 	jsonContentType = "application/json"
 	defaultBaseURL  = "https://api.akahu.io/v1/"
 	akahuIDHeader   = "X-Akahu-ID"
+
+	defaultMaxResponseSize = 32 << 20
+	maxDrainSize           = 64 << 10
+	maxRawErrorBodySize    = 1 << 10
 )
 
 type Client struct {
@@ -25,11 +32,29 @@ type Client struct {
 	AppSecret   string
 	AppIDToken  string
 
+	// Hooks are called around every request made to the Akahu API, in the order they are listed.
+	Hooks []RequestHook
+	// Middleware wraps the HTTP client used to send requests to the Akahu API.
+	// The first middleware in the list is the outermost, and so sees each request first.
+	Middleware []Middleware
+	// IdempotencyKeys remembers the idempotency keys of in-flight payments and transfers. Defaults to a MemoryIdempotencyKeyStore.
+	IdempotencyKeys IdempotencyKeyStore
+	// Tokens holds user access tokens for ForUserID. Defaults to a MemoryTokenStore.
+	Tokens TokenStore
+	// MaxResponseSize is the largest response body that is read, larger responses return ErrResponseTooLarge.
+	// Defaults to 32MB.
+	MaxResponseSize int64
+	// TransactionIdsBatchSize is the most ids that TransactionsService.GetByIds and LookupByIds send in one request.
+	// Defaults to 100.
+	TransactionIdsBatchSize int
+
 	Accounts     *AccountsService
 	Auth         *AuthService
 	Me           *MeService
 	Connections  *ConnectionsService
+	Payments     *PaymentsService
 	Transactions *TransactionsService
+	Transfers    *TransfersService
 	Webhooks     *WebhooksService
 }
 
@@ -44,7 +69,26 @@ type itemResponse[T any] struct {
 
 type collectionResponse[T any] struct {
 	successResponse
-	Items []T `json:"items"`
+	Items  []T             `json:"items"`
+	Cursor *cursorResponse `json:"cursor,omitempty"`
+}
+
+// paginated is implemented by the collection envelope, so that the cursor of the next page can be set on the APIResponse.
+type paginated interface {
+	nextCursor() string
+}
+
+type cursorResponse struct {
+	Next *string `json:"next"`
+}
+
+// nextCursor returns the cursor of the next page of a paginated collection, or an empty string if this is the last page.
+func (r *collectionResponse[T]) nextCursor() string {
+	if r.Cursor == nil || r.Cursor.Next == nil {
+		return ""
+	}
+
+	return *r.Cursor.Next
 }
 
 type errorResponse struct {
@@ -56,6 +100,9 @@ type errorResponse struct {
 type APIResponse struct {
 	Success bool
 	Message string
+	// NextCursor is the cursor of the next page of a paginated endpoint, or empty if there are no more pages.
+	NextCursor string
+	// Response is the HTTP response from Akahu. Its body has already been read and closed.
 	*http.Response
 }
 
@@ -96,12 +143,17 @@ func NewClient(httpClient *http.Client, appIDToken, appSecret, redirectUri strin
 		RedirectURI: parsedRedirectUri,
 		AppIDToken:  appIDToken,
 		AppSecret:   appSecret,
+
+		IdempotencyKeys: NewMemoryIdempotencyKeyStore(),
+		Tokens:          NewMemoryTokenStore(),
 	}
 	c.Accounts = &AccountsService{client: c}
 	c.Auth = &AuthService{client: c}
 	c.Me = &MeService{client: c}
 	c.Connections = &ConnectionsService{client: c}
+	c.Payments = &PaymentsService{client: c}
 	c.Transactions = &TransactionsService{client: c}
+	c.Transfers = &TransfersService{client: c}
 	c.Webhooks = &WebhooksService{client: c}
 
 	return c
@@ -141,18 +193,65 @@ func (c *Client) newRequest(method, urlPath string, body interface{}, requestCon
 	return req, nil
 }
 
-func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*APIResponse, error) {
-	res, err := c.client.Do(req.WithContext(ctx))
+func (c *Client) do(ctx context.Context, operation string, req *http.Request, v interface{}) (*APIResponse, error) {
+	info := &RequestInfo{
+		Operation: operation,
+		Method:    req.Method,
+		Endpoint:  req.URL.Path,
+	}
+	ctx = withOperation(ctx, operation)
+	for _, h := range c.Hooks {
+		ctx = h.BeforeRequest(ctx, info)
+	}
+
+	start := time.Now()
+	res, err := c.send(ctx, req, v)
+	info.Duration = time.Since(start)
+	info.Err = err
+
+	if res == nil {
+		info.StatusCode = errorStatusCode(err)
+	} else {
+		info.StatusCode = res.StatusCode
+		if counter, ok := v.(resultCounter); ok && res.Success {
+			info.ResultCount = counter.resultCount()
+		}
+		if paginated, ok := v.(paginated); ok && res.Success {
+			res.NextCursor = paginated.nextCursor()
+		}
+	}
+
+	for i := len(c.Hooks) - 1; i >= 0; i-- {
+		c.Hooks[i].AfterRequest(ctx, info)
+	}
+
+	return res, err
+}
+
+func (c *Client) send(ctx context.Context, req *http.Request, v interface{}) (*APIResponse, error) {
+	res, err := chain(c.client, c.Middleware).Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
+	defer drainAndClose(res.Body)
 
-	decoder := json.NewDecoder(res.Body)
+	maxSize := c.MaxResponseSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxResponseSize
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, &ResponseTooLargeError{StatusCode: res.StatusCode, Limit: maxSize}
+	}
 
 	if res.StatusCode >= 200 && res.StatusCode <= 299 {
-		err = decoder.Decode(&v)
+		err = json.Unmarshal(body, &v)
 		if err != nil {
-			return nil, err
+			return nil, newRawResponseError(res, body, err)
 		}
 
 		return &APIResponse{
@@ -162,9 +261,9 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*API
 	}
 
 	var errResp errorResponse
-	err = decoder.Decode(&errResp)
+	err = json.Unmarshal(body, &errResp)
 	if err != nil {
-		return nil, err
+		return nil, newRawResponseError(res, body, err)
 	}
 
 	var message string
@@ -180,3 +279,83 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*API
 		Response: res,
 	}, nil
 }
+
+// drainAndClose reads what is left of a response body, up to a limit, before closing it so that the connection can
+// be reused. Connections with more left to read are closed rather than tying up the caller.
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainSize))
+	_ = body.Close()
+}
+
+// ErrResponseTooLarge is returned, as a *ResponseTooLargeError, when a response body is larger than
+// Client.MaxResponseSize.
+var ErrResponseTooLarge = errors.New("akahu: response too large")
+
+// ResponseTooLargeError is returned when a response body is larger than Client.MaxResponseSize. It matches
+// ErrResponseTooLarge with errors.Is.
+type ResponseTooLargeError struct {
+	StatusCode int
+	// Limit is the Client.MaxResponseSize that the body exceeded.
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%v: more than %d bytes with status %d", ErrResponseTooLarge, e.Limit, e.StatusCode)
+}
+
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// errorStatusCode returns the status code of a response that was received but couldn't be read, so that hooks see it
+// even though there is no APIResponse.
+func errorStatusCode(err error) int {
+	var tooLarge *ResponseTooLargeError
+	if errors.As(err, &tooLarge) {
+		return tooLarge.StatusCode
+	}
+
+	var raw *RawResponseError
+	if errors.As(err, &raw) {
+		return raw.StatusCode
+	}
+
+	return 0
+}
+
+// RawResponseError is returned when a response body isn't the JSON expected from Akahu, such as an HTML error page
+// from a proxy in front of it.
+type RawResponseError struct {
+	StatusCode  int
+	ContentType string
+	// Body is the raw response body, truncated to 1KB.
+	Body string
+	// Err is the error from decoding the body as JSON.
+	Err error
+}
+
+func newRawResponseError(res *http.Response, body []byte, err error) *RawResponseError {
+	if len(body) > maxRawErrorBodySize {
+		body = body[:maxRawErrorBodySize]
+	}
+
+	return &RawResponseError{
+		StatusCode:  res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+		Body:        string(body),
+		Err:         err,
+	}
+}
+
+func (e *RawResponseError) Error() string {
+	body := strings.TrimSpace(e.Body)
+	if body == "" {
+		return fmt.Sprintf("akahu: unexpected empty response with status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("akahu: unexpected non-JSON response with status %d: %s", e.StatusCode, body)
+}
+
+func (e *RawResponseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,149 @@
+package akahu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClient_ReusesConnections(t *testing.T) {
+	responses := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, fmt.Sprintf(collectionResponseJson, `{ "_id": "conn_1", "name": "Bank" }`)},
+		// Trailing data after the JSON must still be drained.
+		{http.StatusOK, fmt.Sprintf(collectionResponseJson, "") + strings.Repeat(" ", 4096)},
+		{http.StatusBadRequest, errorResponseJsonWithMessage},
+		{http.StatusBadGateway, "<html><body>502 Bad Gateway</body></html>"},
+		// Larger than MaxResponseSize, so the rest of the body is drained rather than read.
+		{http.StatusOK, fmt.Sprintf(collectionResponseJson, strings.Repeat(`{ "_id": "conn_1" },`, 200)+`{ "_id": "conn_2" }`)},
+	}
+
+	var requests, connections int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := responses[int(atomic.AddInt64(&requests, 1)-1)%len(responses)]
+		w.WriteHeader(response.status)
+		_, _ = io.WriteString(w, response.body)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient(server.Client(), "app_token_123", "appSecret123", "")
+	client.BaseURL, _ = client.BaseURL.Parse(server.URL + "/v1/")
+	client.MaxResponseSize = 1024
+
+	for i := 0; i < 20; i++ {
+		_, _, _ = client.Connections.List(context.TODO())
+	}
+
+	if requests != 20 {
+		t.Fatalf("expected 20 requests, actual %d", requests)
+	}
+	if connections != 1 {
+		t.Fatalf("expected 1 connection to be reused for every request, actual %d", connections)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestClient_ClosesBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "with success response", status: http.StatusOK, body: fmt.Sprintf(collectionResponseJson, "")},
+		{name: "with error response", status: http.StatusBadRequest, body: errorResponseJsonWithMessage},
+		{name: "with non-JSON response", status: http.StatusBadGateway, body: "Bad Gateway"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := &closeRecorder{Reader: strings.NewReader(test.body)}
+			mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: test.status, Body: body}, nil
+			})}
+			client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+			_, _, _ = client.Connections.List(context.TODO())
+
+			if !body.closed {
+				t.Fatalf("expected response body to be closed")
+			}
+		})
+	}
+}
+
+func TestClient_RawResponseError(t *testing.T) {
+	html := "<html><body><h1>502 Bad Gateway</h1></body></html>"
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusBadGateway,
+			Header:     http.Header{"Content-Type": []string{"text/html"}},
+			Body:       io.NopCloser(strings.NewReader(html)),
+		}, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+	hook := &recordingHook{calls: &[]string{}}
+	client.Hooks = append(client.Hooks, hook)
+
+	_, res, err := client.Connections.List(context.TODO())
+	if res != nil {
+		t.Fatalf("expected no APIResponse, actual %+v", res)
+	}
+
+	var rawErr *RawResponseError
+	if !errors.As(err, &rawErr) {
+		t.Fatalf("expected *RawResponseError, actual %v", err)
+	}
+	if rawErr.StatusCode != http.StatusBadGateway || rawErr.ContentType != "text/html" || rawErr.Body != html {
+		t.Fatalf("unexpected error %+v", rawErr)
+	}
+	if !strings.Contains(err.Error(), "502 Bad Gateway") {
+		t.Fatalf("expected error to include the body, actual %v", err)
+	}
+	if hook.infos[0].StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected hook status code %d, actual %d", http.StatusBadGateway, hook.infos[0].StatusCode)
+	}
+}
+
+func TestClient_MaxResponseSize(t *testing.T) {
+	client := setupClient(t, fmt.Sprintf(collectionResponseJson, strings.Repeat(`{ "_id": "conn_1" },`, 100)+`{ "_id": "conn_2" }`), http.MethodGet, http.StatusOK)
+	client.MaxResponseSize = 1024
+	hook := &recordingHook{calls: &[]string{}}
+	client.Hooks = append(client.Hooks, hook)
+
+	_, _, err := client.Connections.List(context.TODO())
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected err %v, actual %v", ErrResponseTooLarge, err)
+	}
+
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.StatusCode != http.StatusOK || tooLarge.Limit != 1024 {
+		t.Fatalf("unexpected error %+v", err)
+	}
+	if hook.infos[0].StatusCode != http.StatusOK {
+		t.Fatalf("expected hook status code %d, actual %d", http.StatusOK, hook.infos[0].StatusCode)
+	}
+}
@@ -3,7 +3,6 @@ package akahu
 import (
 	"context"
 	"net/http"
-	"path"
 )
 
 const connectionsPath = "connections"
@@ -11,10 +10,10 @@ const connectionsPath = "connections"
 type ConnectionsService service
 
 type ConnectionResponse struct {
-	Id   string  `json:"_id"`
-	Name string  `json:"name"`
-	Url  *string `json:"url"`
-	Logo string  `json:"logo"`
+	Id   ConnectionID `json:"_id"`
+	Name string       `json:"name"`
+	Url  *string      `json:"url"`
+	Logo string       `json:"logo"`
 }
 
 // List Gets a list of all connected financial institutions that users can connect to your Akahu application.
@@ -27,7 +26,7 @@ func (s *ConnectionsService) List(ctx context.Context) ([]ConnectionResponse, *A
 	}
 
 	var connections collectionResponse[ConnectionResponse]
-	res, err := s.client.do(ctx, r, &connections)
+	res, err := s.client.do(ctx, "akahu.Connections.List", r, &connections)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -38,14 +37,18 @@ func (s *ConnectionsService) List(ctx context.Context) ([]ConnectionResponse, *A
 // Get fetches an individual financial institution connection.
 //
 // Akahu docs: https://developers.akahu.nz/reference/get_connections-id
-func (s *ConnectionsService) Get(ctx context.Context, connectionId string) (*ConnectionResponse, *APIResponse, error) {
-	r, err := s.client.newRequest(http.MethodGet, path.Join(connectionsPath, connectionId), nil, withBasicAuthRequestConfig())
+func (s *ConnectionsService) Get(ctx context.Context, connectionId ConnectionID) (*ConnectionResponse, *APIResponse, error) {
+	if err := checkID("connectionID", connectionId); err != nil {
+		return nil, nil, err
+	}
+
+	r, err := s.client.newRequest(http.MethodGet, joinPath(connectionsPath, string(connectionId)), nil, withBasicAuthRequestConfig())
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var connections itemResponse[ConnectionResponse]
-	res, err := s.client.do(ctx, r, &connections)
+	res, err := s.client.do(ctx, "akahu.Connections.Get", r, &connections)
 	if err != nil {
 		return nil, nil, err
 	}
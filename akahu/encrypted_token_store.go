@@ -0,0 +1,253 @@
+package akahu
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const encryptedTokenVersion = "v1"
+
+// KeyProvider supplies the AES keys used by EncryptedTokenStore. Keys must be 16, 24 or 32 bytes long,
+// and are identified by an ID so tokens can still be decrypted after the current key is rotated.
+type KeyProvider interface {
+	// CurrentKey returns the ID and value of the key that tokens are encrypted with.
+	CurrentKey(ctx context.Context) (string, []byte, error)
+	// Key returns the key with the given ID, which may no longer be the current key.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider for a fixed set of keys, e.g. loaded from configuration at startup.
+type StaticKeyProvider struct {
+	// CurrentID is the ID of the key in Keys that tokens are encrypted with.
+	CurrentID string
+	Keys      map[string][]byte
+}
+
+func (p StaticKeyProvider) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := p.Key(ctx, p.CurrentID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return p.CurrentID, key, nil
+}
+
+func (p StaticKeyProvider) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := p.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("akahu: unknown encryption key %q", id)
+	}
+
+	return key, nil
+}
+
+// EncryptedTokenStore is a TokenStore that encrypts access tokens with AES-GCM before saving them to another TokenStore.
+// Each token is bound to its user ID, so an encrypted token copied to a different user will fail to decrypt.
+type EncryptedTokenStore struct {
+	store TokenStore
+	keys  KeyProvider
+	// mu serialises writes, so that Reencrypt can't overwrite a token that is put or deleted while it runs.
+	mu sync.Mutex
+}
+
+var _ TokenStore = (*EncryptedTokenStore)(nil)
+
+// NewEncryptedTokenStore creates an EncryptedTokenStore that saves encrypted tokens to store, using keys from keys.
+func NewEncryptedTokenStore(store TokenStore, keys KeyProvider) *EncryptedTokenStore {
+	return &EncryptedTokenStore{store: store, keys: keys}
+}
+
+func (s *EncryptedTokenStore) Get(ctx context.Context, userID string) (string, error) {
+	encrypted, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	token, _, err := s.decrypt(ctx, userID, encrypted)
+	return token, err
+}
+
+func (s *EncryptedTokenStore) Put(ctx context.Context, userID, userAccessToken string) error {
+	encrypted, err := s.encrypt(ctx, userID, userAccessToken)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.store.Put(ctx, userID, encrypted)
+}
+
+func (s *EncryptedTokenStore) Delete(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.store.Delete(ctx, userID)
+}
+
+// DeleteToken decrypts every stored token to find the users that the token belongs to. Tokens that can't be decrypted
+// are skipped, and their errors returned once every other token has been checked.
+func (s *EncryptedTokenStore) DeleteToken(ctx context.Context, userAccessToken string) error {
+	userIDs, err := s.store.UserIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, userID := range userIDs {
+		token, err := s.Get(ctx, userID)
+		if errors.Is(err, ErrTokenNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if token == userAccessToken {
+			if err := s.Delete(ctx, userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *EncryptedTokenStore) UserIDs(ctx context.Context) ([]string, error) {
+	return s.store.UserIDs(ctx)
+}
+
+// Reencrypt re-encrypts every token that was not encrypted with the current key, returning how many were updated.
+// Run it after rotating keys, after which the previous key can be retired from the KeyProvider. Tokens that can't be
+// decrypted are left as they are, and their errors returned once every other token has been re-encrypted.
+func (s *EncryptedTokenStore) Reencrypt(ctx context.Context) (int, error) {
+	currentID, _, err := s.keys.CurrentKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	userIDs, err := s.store.UserIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	var errs []error
+	for _, userID := range userIDs {
+		ok, err := s.reencrypt(ctx, userID, currentID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+
+	return updated, errors.Join(errs...)
+}
+
+// reencrypt re-encrypts the user's token if it wasn't encrypted with the current key, reporting whether it did. The
+// token is read and written under the lock, so a token put while it is being re-encrypted isn't overwritten.
+func (s *EncryptedTokenStore) reencrypt(ctx context.Context, userID, currentID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encrypted, err := s.store.Get(ctx, userID)
+	if errors.Is(err, ErrTokenNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	token, keyID, err := s.decrypt(ctx, userID, encrypted)
+	if err != nil || keyID == currentID {
+		return false, err
+	}
+
+	reencrypted, err := s.encrypt(ctx, userID, token)
+	if err != nil {
+		return false, err
+	}
+
+	return true, s.store.Put(ctx, userID, reencrypted)
+}
+
+// encrypt returns the token encrypted as "v1:<key ID>:<base64 nonce and ciphertext>".
+func (s *EncryptedTokenStore) encrypt(ctx context.Context, userID, token string) (string, error) {
+	keyID, key, err := s.keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(keyID, ":") {
+		return "", fmt.Errorf("akahu: encryption key ID %q must not contain ':'", keyID)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(token), []byte(userID))
+
+	return strings.Join([]string{encryptedTokenVersion, keyID, base64.StdEncoding.EncodeToString(sealed)}, ":"), nil
+}
+
+func (s *EncryptedTokenStore) decrypt(ctx context.Context, userID, encrypted string) (string, string, error) {
+	parts := strings.Split(encrypted, ":")
+	if len(parts) != 3 || parts[0] != encryptedTokenVersion {
+		return "", "", errors.New("akahu: malformed encrypted token")
+	}
+	keyID := parts[1]
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", "", err
+	}
+
+	key, err := s.keys.Key(ctx, keyID)
+	if err != nil {
+		return "", "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", "", errors.New("akahu: malformed encrypted token")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	token, err := gcm.Open(nil, nonce, ciphertext, []byte(userID))
+	if err != nil {
+		return "", "", fmt.Errorf("akahu: decrypting token for user %s: %w", userID, err)
+	}
+
+	return string(token), keyID, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
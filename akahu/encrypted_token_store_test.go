@@ -0,0 +1,133 @@
+package akahu
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testKey1 = bytes.Repeat([]byte{1}, 32)
+	testKey2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestEncryptedTokenStore(t *testing.T) {
+	ctx := context.TODO()
+	backing := NewMemoryTokenStore()
+	keys := &StaticKeyProvider{CurrentID: "key1", Keys: map[string][]byte{"key1": testKey1}}
+	store := NewEncryptedTokenStore(backing, keys)
+
+	_ = store.Put(ctx, "user_1", "user_token_1")
+	_ = store.Put(ctx, "user_2", "user_token_2")
+
+	encrypted, _ := backing.Get(ctx, "user_1")
+	if strings.Contains(encrypted, "user_token_1") || !strings.HasPrefix(encrypted, "v1:key1:") {
+		t.Fatalf("expected token to be encrypted with key1, actual %s", encrypted)
+	}
+
+	token, err := store.Get(ctx, "user_1")
+	testClientResponse(t, "user_token_1", token, err)
+
+	// A token copied to another user must not decrypt.
+	_ = backing.Put(ctx, "user_3", encrypted)
+	if _, err := store.Get(ctx, "user_3"); err == nil {
+		t.Fatalf("expected error decrypting token copied to a different user")
+	}
+	_ = backing.Delete(ctx, "user_3")
+
+	// A token that can't be decrypted doesn't stop the others being checked.
+	_ = backing.Put(ctx, "user_0", "v1:retired:AAAA")
+	if err := store.DeleteToken(ctx, "user_token_2"); err == nil {
+		t.Fatalf("expected error decrypting the token for user_0")
+	}
+	userIDs, err := store.UserIDs(ctx)
+	testClientResponse(t, []string{"user_0", "user_1"}, userIDs, err)
+}
+
+func TestEncryptedTokenStore_Reencrypt(t *testing.T) {
+	ctx := context.TODO()
+	backing := NewMemoryTokenStore()
+	keys := &StaticKeyProvider{CurrentID: "key1", Keys: map[string][]byte{"key1": testKey1}}
+	store := NewEncryptedTokenStore(backing, keys)
+
+	_ = store.Put(ctx, "user_1", "user_token_1")
+	_ = store.Put(ctx, "user_2", "user_token_2")
+
+	keys.Keys["key2"] = testKey2
+	keys.CurrentID = "key2"
+
+	_ = store.Put(ctx, "user_3", "user_token_3")
+	_ = backing.Put(ctx, "user_0", "v1:retired:AAAA")
+
+	// The token that can't be decrypted is left as it is, and the rest are still re-encrypted.
+	updated, err := store.Reencrypt(ctx)
+	if err == nil {
+		t.Fatalf("expected error decrypting the token for user_0")
+	}
+	if updated != 2 {
+		t.Fatalf("expected 2 tokens re-encrypted, actual %d", updated)
+	}
+	if encrypted, _ := backing.Get(ctx, "user_0"); encrypted != "v1:retired:AAAA" {
+		t.Fatalf("expected token for user_0 to be unchanged, actual %s", encrypted)
+	}
+
+	delete(keys.Keys, "key1")
+
+	for userID, expected := range map[string]string{"user_1": "user_token_1", "user_2": "user_token_2", "user_3": "user_token_3"} {
+		encrypted, _ := backing.Get(ctx, userID)
+		if !strings.HasPrefix(encrypted, "v1:key2:") {
+			t.Fatalf("expected token for %s to be encrypted with key2, actual %s", userID, encrypted)
+		}
+
+		token, err := store.Get(ctx, userID)
+		testClientResponse(t, expected, token, err)
+	}
+}
+
+// putDuringGetStore puts a new token from another goroutine each time a token is read, if put is set.
+type putDuringGetStore struct {
+	TokenStore
+	put func()
+	wg  sync.WaitGroup
+}
+
+func (s *putDuringGetStore) Get(ctx context.Context, userID string) (string, error) {
+	token, err := s.TokenStore.Get(ctx, userID)
+	if s.put != nil {
+		put := s.put
+		s.put = nil
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			put()
+		}()
+		// Give the put a chance to run before the token is written back.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return token, err
+}
+
+func TestEncryptedTokenStore_Reencrypt_ConcurrentPut(t *testing.T) {
+	ctx := context.TODO()
+	backing := &putDuringGetStore{TokenStore: NewMemoryTokenStore()}
+	keys := &StaticKeyProvider{CurrentID: "key1", Keys: map[string][]byte{"key1": testKey1, "key2": testKey2}}
+	store := NewEncryptedTokenStore(backing, keys)
+
+	_ = store.Put(ctx, "user_1", "user_token_1")
+	keys.CurrentID = "key2"
+
+	// The token is replaced while Reencrypt holds the old one, which must not be written back over the new one.
+	backing.put = func() { _ = store.Put(ctx, "user_1", "user_token_2") }
+	_, err := store.Reencrypt(ctx)
+	backing.wg.Wait()
+	if err != nil {
+		t.Fatalf("Reencrypt returned err %v", err)
+	}
+
+	token, err := store.Get(ctx, "user_1")
+	testClientResponse(t, "user_token_2", token, err)
+}
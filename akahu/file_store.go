@@ -0,0 +1,87 @@
+package akahu
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// jsonFileMap persists a map of strings to a JSON file, which backs the file based stores.
+// Writes go to a temporary file that is renamed over the original, so a crash never leaves a partially written file.
+type jsonFileMap struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (f *jsonFileMap) read() (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.load()
+}
+
+func (f *jsonFileMap) update(fn func(m map[string]string) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(m); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(f.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, syncs it and renames it over path, so a crash never
+// leaves a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *jsonFileMap) load() (map[string]string, error) {
+	m := map[string]string{}
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,54 @@
+package akahu
+
+import (
+	"context"
+	"time"
+)
+
+// RequestInfo describes a single call made by the Client to the Akahu API.
+// The request fields are populated before RequestHook.BeforeRequest is called,
+// the result fields are populated before RequestHook.AfterRequest is called.
+type RequestInfo struct {
+	// Operation is the name of the service method that made the call, e.g. "akahu.Transactions.List".
+	Operation string
+	Method    string
+	// Endpoint is the path of the request URL, without query parameters.
+	Endpoint string
+
+	// StatusCode is the status of the response, including one that couldn't be read, such as a *RawResponseError or
+	// *ResponseTooLargeError. It is 0 if no response was received.
+	StatusCode int
+	// ResultCount is the number of items returned by the call, 0 if the call failed.
+	ResultCount int
+	Duration    time.Duration
+	// Err is the transport or decoding error returned by the call, if any.
+	// Unsuccessful API responses are reported through StatusCode rather than Err.
+	Err error
+}
+
+// RequestHook observes every call made by the Client, which allows tracing and metrics to be
+// added without the akahu package depending on a particular instrumentation library.
+type RequestHook interface {
+	// BeforeRequest is called before the request is sent. The returned context is used for the request
+	// and is passed to AfterRequest, so it can carry a span or any other per-request state.
+	BeforeRequest(ctx context.Context, info *RequestInfo) context.Context
+	// AfterRequest is called once the response has been decoded, or the request has failed.
+	AfterRequest(ctx context.Context, info *RequestInfo)
+}
+
+// resultCounter is implemented by the response envelopes so hooks can report how many items a call returned.
+type resultCounter interface {
+	resultCount() int
+}
+
+func (r *itemResponse[T]) resultCount() int {
+	if r.Item == nil {
+		return 0
+	}
+
+	return 1
+}
+
+func (r *collectionResponse[T]) resultCount() int {
+	return len(r.Items)
+}
@@ -0,0 +1,88 @@
+package akahu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type hookCtxKey struct{}
+
+type recordingHook struct {
+	name  string
+	calls *[]string
+	infos []RequestInfo
+	ctxOk bool
+}
+
+func (h *recordingHook) BeforeRequest(ctx context.Context, info *RequestInfo) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	return context.WithValue(ctx, hookCtxKey{}, h.name)
+}
+
+func (h *recordingHook) AfterRequest(ctx context.Context, info *RequestInfo) {
+	*h.calls = append(*h.calls, "after "+h.name)
+	h.ctxOk = ctx.Value(hookCtxKey{}) != nil
+	h.infos = append(h.infos, *info)
+}
+
+func TestClient_Hooks(t *testing.T) {
+	tests := []struct {
+		name                string
+		jsonResponse        string
+		statusCode          int
+		expectedResultCount int
+	}{
+		{
+			name:                "with collection response",
+			jsonResponse:        fmt.Sprintf(collectionResponseJson, unenrichedTransactionJson+","+enrichedTransactionJson),
+			statusCode:          http.StatusOK,
+			expectedResultCount: 2,
+		},
+		{
+			name:                "with error response",
+			jsonResponse:        errorResponseJsonWithMessage,
+			statusCode:          http.StatusBadRequest,
+			expectedResultCount: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			first := &recordingHook{name: "first", calls: &calls}
+			second := &recordingHook{name: "second", calls: &calls}
+
+			client := setupClient(t, test.jsonResponse, http.MethodGet, test.statusCode)
+			client.Hooks = []RequestHook{first, second}
+
+			_, _, err := client.Transactions.List(context.TODO(), "user_token_1", time.Now(), time.Now())
+			if err != nil {
+				t.Fatalf("client request returned err %v", err)
+			}
+
+			expectedCalls := []string{"before first", "before second", "after second", "after first"}
+			testClientResponse(t, expectedCalls, calls, nil)
+
+			if !first.ctxOk {
+				t.Fatalf("expected context returned by BeforeRequest to be passed to AfterRequest")
+			}
+
+			info := first.infos[0]
+			if info.Operation != "akahu.Transactions.List" {
+				t.Fatalf("expected operation %s, actual %s", "akahu.Transactions.List", info.Operation)
+			}
+			if info.Endpoint != "/v1/transactions" {
+				t.Fatalf("expected endpoint %s, actual %s", "/v1/transactions", info.Endpoint)
+			}
+			if info.StatusCode != test.statusCode {
+				t.Fatalf("expected status code %d, actual %d", test.statusCode, info.StatusCode)
+			}
+			if info.ResultCount != test.expectedResultCount {
+				t.Fatalf("expected result count %d, actual %d", test.expectedResultCount, info.ResultCount)
+			}
+		})
+	}
+}
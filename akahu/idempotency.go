@@ -0,0 +1,194 @@
+package akahu
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyOptions controls the Idempotency-Key header sent when creating a payment or transfer.
+// Akahu will only action one request for a given key, so the same key must be used when a request is retried.
+type IdempotencyOptions struct {
+	// Key is sent as the Idempotency-Key header. A random key is generated if it is empty.
+	Key string
+	// Reference identifies the operation in your own system, e.g. an invoice ID.
+	// When set, the key used for the operation is saved to Client.IdempotencyKeys until Akahu has given a
+	// definitive response, so a worker that crashes mid-request reuses the same key when it resumes the operation.
+	Reference string
+}
+
+// IdempotencyKeyStore remembers the idempotency keys of in-flight operations by their reference.
+type IdempotencyKeyStore interface {
+	// Load returns the key saved for the reference, and false if there isn't one.
+	Load(ctx context.Context, reference string) (string, bool, error)
+	Save(ctx context.Context, reference, key string) error
+	Delete(ctx context.Context, reference string) error
+}
+
+// MemoryIdempotencyKeyStore is an IdempotencyKeyStore that holds keys in memory.
+// Keys do not survive a restart, use FileIdempotencyKeyStore or your own implementation if that is required.
+type MemoryIdempotencyKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+// NewMemoryIdempotencyKeyStore creates an empty MemoryIdempotencyKeyStore.
+func NewMemoryIdempotencyKeyStore() *MemoryIdempotencyKeyStore {
+	return &MemoryIdempotencyKeyStore{keys: map[string]string{}}
+}
+
+func (s *MemoryIdempotencyKeyStore) Load(_ context.Context, reference string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[reference]
+	return key, ok, nil
+}
+
+func (s *MemoryIdempotencyKeyStore) Save(_ context.Context, reference, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[reference] = key
+	return nil
+}
+
+func (s *MemoryIdempotencyKeyStore) Delete(_ context.Context, reference string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, reference)
+	return nil
+}
+
+// FileIdempotencyKeyStore is an IdempotencyKeyStore that persists keys to a JSON file.
+type FileIdempotencyKeyStore struct {
+	file jsonFileMap
+}
+
+// NewFileIdempotencyKeyStore creates a FileIdempotencyKeyStore backed by the file at path, which is created if it doesn't exist.
+func NewFileIdempotencyKeyStore(path string) *FileIdempotencyKeyStore {
+	return &FileIdempotencyKeyStore{file: jsonFileMap{path: path}}
+}
+
+func (s *FileIdempotencyKeyStore) Load(_ context.Context, reference string) (string, bool, error) {
+	keys, err := s.file.read()
+	if err != nil {
+		return "", false, err
+	}
+
+	key, ok := keys[reference]
+	return key, ok, nil
+}
+
+func (s *FileIdempotencyKeyStore) Save(_ context.Context, reference, key string) error {
+	return s.file.update(func(keys map[string]string) error {
+		keys[reference] = key
+		return nil
+	})
+}
+
+func (s *FileIdempotencyKeyStore) Delete(_ context.Context, reference string) error {
+	return s.file.update(func(keys map[string]string) error {
+		delete(keys, reference)
+		return nil
+	})
+}
+
+// NewIdempotencyKey generates a random (version 4) UUID to use as an idempotency key.
+func NewIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func withIdempotencyKeyRequestConfig(key string) requestConfig {
+	return func(req *http.Request, c *Client) {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+}
+
+// idempotencyKey resolves the key to send for opts, reusing or saving the key for opts.Reference.
+func (c *Client) idempotencyKey(ctx context.Context, opts IdempotencyOptions) (string, error) {
+	if opts.Reference == "" {
+		if opts.Key != "" {
+			return opts.Key, nil
+		}
+		return NewIdempotencyKey()
+	}
+
+	key, ok, err := c.IdempotencyKeys.Load(ctx, opts.Reference)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return key, nil
+	}
+
+	key = opts.Key
+	if key == "" {
+		key, err = NewIdempotencyKey()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := c.IdempotencyKeys.Save(ctx, opts.Reference, key); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// releaseIdempotencyKey forgets the key for opts.Reference once Akahu has given a definitive response.
+// The key is kept if the outcome is unknown, e.g. the request timed out or Akahu asked for it to be retried.
+func (c *Client) releaseIdempotencyKey(ctx context.Context, opts IdempotencyOptions, res *APIResponse) error {
+	if opts.Reference == "" || res == nil {
+		return nil
+	}
+
+	if res.StatusCode == http.StatusConflict || res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError {
+		return nil
+	}
+
+	return c.IdempotencyKeys.Delete(ctx, opts.Reference)
+}
+
+type createResponse struct {
+	successResponse
+	ItemId *string `json:"item_id"`
+}
+
+// createIdempotent POSTs body with an Idempotency-Key header and returns the ID of the created item.
+func (c *Client) createIdempotent(ctx context.Context, operation, urlPath, userAccessToken string, body interface{}, opts IdempotencyOptions) (*string, *APIResponse, error) {
+	key, err := c.idempotencyKey(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, err := c.newRequest(http.MethodPost, urlPath, body, withTokenRequestConfig(userAccessToken), withIdempotencyKeyRequestConfig(key))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var created createResponse
+	res, err := c.do(ctx, operation, r, &created)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := c.releaseIdempotencyKey(ctx, opts, res); err != nil {
+		return nil, res, err
+	}
+
+	return created.ItemId, res, nil
+}
@@ -0,0 +1,129 @@
+package akahu
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTransfersService_Create(t *testing.T) {
+	var keys []string
+	var bodies []string
+	statusCodes := []int{http.StatusServiceUnavailable, http.StatusOK}
+
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		testTokenRequestHeaders(t, req, "app_token_123", "user_token_1")
+
+		body, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+
+		status := statusCodes[len(keys)-1]
+		jsonResponse := "{ \"success\": true, \"item_id\": \"transfer_1111111111111111111111111\" }"
+		if status != http.StatusOK {
+			jsonResponse = errorResponseJsonWithMessage
+		}
+
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(jsonResponse)),
+		}, nil
+	})}
+
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+	client.Middleware = []Middleware{RetryMiddleware(RetryOptions{BaseDelay: time.Millisecond})}
+
+	body := TransferRequest{
+		From:   "acc_1111111111111111111111111",
+		To:     "acc_2222222222222222222222222",
+		Amount: decimal.RequireFromString("10.50"),
+	}
+	actual, res, err := client.Transfers.Create(context.TODO(), "user_token_1", body, IdempotencyOptions{Reference: "invoice_1"})
+
+	expectedId := "transfer_1111111111111111111111111"
+	testClientResponse(t, &expectedId, actual, err)
+	testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 attempts, actual %d", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("expected the same idempotency key on each attempt, actual %v", keys)
+	}
+
+	expectedBody := "{\"from\":\"acc_1111111111111111111111111\",\"to\":\"acc_2222222222222222222222222\",\"amount\":10.5}\n"
+	if bodies[1] != expectedBody {
+		t.Fatalf("expected request body %s, actual %s", expectedBody, bodies[1])
+	}
+
+	if _, ok, _ := client.IdempotencyKeys.Load(context.TODO(), "invoice_1"); ok {
+		t.Fatalf("expected idempotency key to be released after a successful response")
+	}
+}
+
+func TestPaymentsService_Create_ResumesWithSavedKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		jsonResponse  string
+		expectedSaved bool
+	}{
+		{
+			name:          "with success response",
+			statusCode:    http.StatusOK,
+			jsonResponse:  "{ \"success\": true, \"item_id\": \"payment_1111111111111111111111111\" }",
+			expectedSaved: false,
+		},
+		{
+			name:          "with client error response",
+			statusCode:    http.StatusBadRequest,
+			jsonResponse:  errorResponseJsonWithMessage,
+			expectedSaved: false,
+		},
+		{
+			name:          "with server error response",
+			statusCode:    http.StatusInternalServerError,
+			jsonResponse:  errorResponseJsonWithMessage,
+			expectedSaved: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewFileIdempotencyKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+			// A key saved by a worker that crashed before receiving a response.
+			_ = store.Save(context.TODO(), "invoice_1", "key_from_crashed_worker")
+
+			client := setupClient(t, test.jsonResponse, http.MethodPost, test.statusCode, func(r *http.Request) {
+				if key := r.Header.Get("Idempotency-Key"); key != "key_from_crashed_worker" {
+					t.Fatalf("expected header Idempotency-Key %s, actual %s", "key_from_crashed_worker", key)
+				}
+			})
+			client.IdempotencyKeys = store
+
+			body := PaymentRequest{
+				From:   "acc_1111111111111111111111111",
+				Amount: decimal.NewFromInt(20),
+				To: PaymentRecipient{
+					Name:          "Bob",
+					AccountNumber: "12-3456-7890123-00",
+				},
+			}
+			_, _, err := client.Payments.Create(context.TODO(), "user_token_1", body, IdempotencyOptions{Reference: "invoice_1"})
+			if err != nil {
+				t.Fatalf("client request returned err %v", err)
+			}
+
+			if _, saved, _ := store.Load(context.TODO(), "invoice_1"); saved != test.expectedSaved {
+				t.Fatalf("expected key saved %t, actual %t", test.expectedSaved, saved)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package akahu
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AccountID identifies one of a user's connected accounts, e.g. acc_1111111111111111111111111.
+type AccountID string
+
+// TransactionID identifies a settled transaction, e.g. trans_1111111111111111111111111.
+type TransactionID string
+
+// ConnectionID identifies a financial institution that users can connect, e.g. conn_1111111111111111111111111.
+type ConnectionID string
+
+// WebhookID identifies a webhook subscription, e.g. hook_1111111111111111111111111.
+type WebhookID string
+
+// UserID identifies an Akahu user, e.g. user_1111111111111111111111111.
+type UserID string
+
+// CredentialsID identifies the login a user connected an account with, e.g. creds_1111111111111111111111111.
+type CredentialsID string
+
+const (
+	accountIDPrefix     = "acc_"
+	transactionIDPrefix = "trans_"
+	connectionIDPrefix  = "conn_"
+	webhookIDPrefix     = "hook_"
+	userIDPrefix        = "user_"
+	credentialsIDPrefix = "creds_"
+)
+
+// InvalidIDError is returned when an ID isn't the prefix for its type followed by letters and digits, e.g. when a
+// transaction ID is decoded as an AccountID.
+type InvalidIDError struct {
+	// Type is the name of the ID's type, e.g. "AccountID".
+	Type string
+	// Prefix is the prefix IDs of the type have.
+	Prefix string
+	ID     string
+}
+
+func (e *InvalidIDError) Error() string {
+	return fmt.Sprintf("akahu: invalid %s %q, expected %q followed by letters and digits", e.Type, e.ID, e.Prefix)
+}
+
+// Validate returns an *InvalidIDError unless id is "acc_" followed by letters and digits.
+func (id AccountID) Validate() error {
+	return validateID("AccountID", accountIDPrefix, string(id))
+}
+
+// UnmarshalJSON decodes a JSON string, which must be empty or a valid AccountID.
+func (id *AccountID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, id)
+}
+
+// Validate returns an *InvalidIDError unless id is "trans_" followed by letters and digits.
+func (id TransactionID) Validate() error {
+	return validateID("TransactionID", transactionIDPrefix, string(id))
+}
+
+// UnmarshalJSON decodes a JSON string, which must be empty or a valid TransactionID.
+func (id *TransactionID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, id)
+}
+
+// Validate returns an *InvalidIDError unless id is "conn_" followed by letters and digits.
+func (id ConnectionID) Validate() error {
+	return validateID("ConnectionID", connectionIDPrefix, string(id))
+}
+
+// UnmarshalJSON decodes a JSON string, which must be empty or a valid ConnectionID.
+func (id *ConnectionID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, id)
+}
+
+// Validate returns an *InvalidIDError unless id is "hook_" followed by letters and digits.
+func (id WebhookID) Validate() error {
+	return validateID("WebhookID", webhookIDPrefix, string(id))
+}
+
+// UnmarshalJSON decodes a JSON string, which must be empty or a valid WebhookID.
+func (id *WebhookID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, id)
+}
+
+// Validate returns an *InvalidIDError unless id is "user_" followed by letters and digits.
+func (id UserID) Validate() error {
+	return validateID("UserID", userIDPrefix, string(id))
+}
+
+// UnmarshalJSON decodes a JSON string, which must be empty or a valid UserID.
+func (id *UserID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, id)
+}
+
+// Validate returns an *InvalidIDError unless id is "creds_" followed by letters and digits.
+func (id CredentialsID) Validate() error {
+	return validateID("CredentialsID", credentialsIDPrefix, string(id))
+}
+
+// UnmarshalJSON decodes a JSON string, which must be empty or a valid CredentialsID.
+func (id *CredentialsID) UnmarshalJSON(data []byte) error {
+	return unmarshalID(data, id)
+}
+
+func validateID(typeName, prefix, id string) error {
+	if !strings.HasPrefix(id, prefix) || len(id) == len(prefix) {
+		return &InvalidIDError{Type: typeName, Prefix: prefix, ID: id}
+	}
+
+	for _, r := range id[len(prefix):] {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return &InvalidIDError{Type: typeName, Prefix: prefix, ID: id}
+		}
+	}
+
+	return nil
+}
+
+type validatedID interface {
+	~string
+	Validate() error
+}
+
+// unmarshalID decodes an ID from a JSON string, validating its prefix. Akahu leaves some IDs empty or null, such as the
+// connection of a manual account, so these are allowed.
+func unmarshalID[T validatedID](data []byte, id *T) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == nil || *s == "" {
+		*id = ""
+		return nil
+	}
+
+	if err := T(*s).Validate(); err != nil {
+		return err
+	}
+
+	*id = T(*s)
+	return nil
+}
@@ -0,0 +1,103 @@
+package akahu
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestAccountID_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		json        string
+		expected    AccountID
+		expectedErr bool
+	}{
+		{
+			name:     "with valid id",
+			json:     `"acc_1111111111111111111111111"`,
+			expected: "acc_1111111111111111111111111",
+		},
+		{
+			name: "with empty id",
+			json: `""`,
+		},
+		{
+			name: "with null id",
+			json: `null`,
+		},
+		{
+			name:        "with transaction id",
+			json:        `"trans_1111111111111111111111111"`,
+			expectedErr: true,
+		},
+		{
+			name:        "with prefix only",
+			json:        `"acc_"`,
+			expectedErr: true,
+		},
+		{
+			name:        "with number",
+			json:        `1`,
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual AccountID
+			err := json.Unmarshal([]byte(test.json), &actual)
+
+			if test.expectedErr != (err != nil) {
+				t.Fatalf("expected err %v, actual %v", test.expectedErr, err)
+			}
+			if actual != test.expected {
+				t.Fatalf("expected %q, actual %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTransactionResponse_InvalidID(t *testing.T) {
+	var transaction TransactionResponse
+	err := json.Unmarshal([]byte(`{ "_id": "trans_1111111111111111111111111", "_account": "conn_1111111111111111111111111", "amount": 0, "balance": 0 }`), &transaction)
+
+	var invalid *InvalidIDError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *InvalidIDError, actual %v", err)
+	}
+	if invalid.Type != "AccountID" || invalid.ID != "conn_1111111111111111111111111" {
+		t.Fatalf("unexpected error %+v", invalid)
+	}
+}
+
+func TestIDs_Validate(t *testing.T) {
+	valid := []interface{ Validate() error }{
+		AccountID("acc_1"),
+		TransactionID("trans_1"),
+		ConnectionID("conn_1"),
+		WebhookID("hook_1"),
+		UserID("user_1"),
+		CredentialsID("creds_1"),
+	}
+	for _, id := range valid {
+		if err := id.Validate(); err != nil {
+			t.Fatalf("expected %v to be valid, actual err %v", id, err)
+		}
+	}
+
+	invalid := []interface{ Validate() error }{
+		AccountID("trans_1"),
+		AccountID("acc_1/../me"),
+		TransactionID(""),
+		ConnectionID("acc_1"),
+		WebhookID("1"),
+		UserID("creds_1"),
+		CredentialsID("user_1"),
+	}
+	for _, id := range invalid {
+		if err := id.Validate(); err == nil {
+			t.Fatalf("expected %v to be invalid", id)
+		}
+	}
+}
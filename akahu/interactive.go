@@ -0,0 +1,132 @@
+package akahu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+const interactiveShutdownTimeout = 5 * time.Second
+
+// InteractiveOptions configures AuthorizeInteractive.
+type InteractiveOptions struct {
+	AuthorizationURLOptions
+
+	// Output is where the authorization URL is printed for the user. Defaults to os.Stderr.
+	Output io.Writer
+	// OpenBrowser is called with the authorization URL, e.g. to open it in the user's browser.
+	// The URL is only printed if OpenBrowser is nil or returns an error.
+	OpenBrowser func(authURL string) error
+}
+
+type interactiveResult struct {
+	exchange *ExchangeResponse
+	err      error
+}
+
+// AuthorizeInteractive runs the OAuth authorization flow for command line and desktop tools.
+// It starts an HTTP server on the host and port of the client's redirect URI, which must be a loopback address
+// such as http://localhost:8080/callback, and waits for the user to complete authorization in their browser.
+// The state is verified and the code exchanged with OAuthFlow, and the server is shut down before returning,
+// including when ctx is cancelled.
+func AuthorizeInteractive(ctx context.Context, client *Client, opts InteractiveOptions) (*ExchangeResponse, error) {
+	redirectURI := client.RedirectURI
+	if redirectURI == nil || redirectURI.Scheme != "http" {
+		return nil, errors.New("akahu: interactive authorization requires an http redirect URI")
+	}
+	if !isLoopbackHost(redirectURI.Hostname()) {
+		return nil, fmt.Errorf("akahu: interactive authorization requires a loopback redirect URI, got host %q", redirectURI.Hostname())
+	}
+
+	addr := redirectURI.Host
+	if redirectURI.Port() == "" {
+		addr = net.JoinHostPort(redirectURI.Hostname(), "80")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	flow := NewOAuthFlow(client, NewMemoryStateStore())
+	authURL, _, err := flow.AuthorizationURL(ctx, opts.AuthorizationURLOptions)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	results := make(chan interactiveResult, 1)
+	callbackPath := redirectURI.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != callbackPath {
+				http.NotFound(w, r)
+				return
+			}
+
+			exchange, res, err := flow.HandleCallback(ctx, r)
+			if errors.Is(err, ErrInvalidState) {
+				// Not the callback for this flow, keep waiting for the real one.
+				http.Error(w, "Invalid or expired authorization request.", http.StatusBadRequest)
+				return
+			}
+			if err == nil && exchange == nil {
+				err = fmt.Errorf("akahu: token exchange failed: %s", res.Message)
+			}
+
+			if err != nil {
+				http.Error(w, "Authorization failed, you can close this window.", http.StatusBadRequest)
+			} else {
+				_, _ = io.WriteString(w, "Authorization complete, you can close this window.")
+			}
+
+			select {
+			case results <- interactiveResult{exchange: exchange, err: err}:
+			default:
+			}
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), interactiveShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if opts.OpenBrowser == nil || opts.OpenBrowser(authURL) != nil {
+		output := opts.Output
+		if output == nil {
+			output = os.Stderr
+		}
+		_, _ = fmt.Fprintf(output, "Open the following URL in your browser to authorize access to Akahu:\n\n%s\n\n", authURL)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		return result.exchange, result.err
+	}
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,90 @@
+package akahu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthorizeInteractive(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	client := setupClient(t, exchangeJson, http.MethodPost, http.StatusOK)
+	client.RedirectURI, _ = url.Parse(fmt.Sprintf("http://%s/callback", addr))
+
+	browser := func(authURL string) error {
+		parsed, _ := url.Parse(authURL)
+		state := parsed.Query().Get("state")
+
+		go func() {
+			// A request for this flow with the wrong state must not end it.
+			res, err := http.Get(fmt.Sprintf("http://%s/callback?code=code_1&state=forged", addr))
+			if err == nil {
+				_ = res.Body.Close()
+			}
+
+			res, err = http.Get(fmt.Sprintf("http://%s/callback?code=code_1&state=%s", addr, state))
+			if err != nil {
+				t.Errorf("callback request returned err %v", err)
+				return
+			}
+			body, _ := io.ReadAll(res.Body)
+			_ = res.Body.Close()
+
+			if !strings.Contains(string(body), "Authorization complete") {
+				t.Errorf("expected completion page, actual %s", body)
+			}
+		}()
+
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	actual, err := AuthorizeInteractive(ctx, client, InteractiveOptions{OpenBrowser: browser})
+	expected := &ExchangeResponse{
+		AccessToken: "user_token_1111111111111111111111111",
+		TokenType:   "bearer",
+		Scope:       "IDENTITY_BASIC ACCOUNTS TRANSACTIONS",
+	}
+	testClientResponse(t, expected, actual, err)
+
+	// The server is shut down once authorization completes.
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		_ = conn.Close()
+		t.Fatalf("expected callback server to be shut down")
+	}
+}
+
+func TestAuthorizeInteractive_ContextCancelled(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	client := NewClient(nil, "app_token_123", "appSecret123", fmt.Sprintf("http://%s/callback", addr))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := AuthorizeInteractive(ctx, client, InteractiveOptions{Output: io.Discard})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected err %v, actual %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestAuthorizeInteractive_NonLoopbackRedirect(t *testing.T) {
+	client := NewClient(nil, "app_token_123", "appSecret123", "https://example.com/auth/akahu")
+
+	if _, err := AuthorizeInteractive(context.TODO(), client, InteractiveOptions{Output: io.Discard}); err == nil {
+		t.Fatalf("expected error for non loopback redirect URI")
+	}
+}
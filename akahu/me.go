@@ -9,7 +9,7 @@ import (
 type MeService service
 
 type MeResponse struct {
-	Id            string     `json:"_id"`
+	Id            UserID     `json:"_id"`
 	CreatedAt     *time.Time `json:"created_at"`
 	Email         string     `json:"email"`
 	Mobile        *string    `json:"mobile"`
@@ -28,7 +28,7 @@ func (s *MeService) Get(ctx context.Context, userAccessToken string) (*MeRespons
 	}
 
 	var meResponse itemResponse[MeResponse]
-	res, err := s.client.do(ctx, r, &meResponse)
+	res, err := s.client.do(ctx, "akahu.Me.Get", r, &meResponse)
 	if err != nil {
 		return nil, nil, err
 	}
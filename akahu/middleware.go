@@ -0,0 +1,254 @@
+package akahu
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Doer sends an HTTP request and returns its response. *http.Client implements Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc is an adapter to allow the use of ordinary functions as a Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the Doer used to send requests to the Akahu API, which allows behaviour such as auth refresh,
+// caching or circuit breaking to be added to the Client.
+type Middleware func(next Doer) Doer
+
+type operationContextKey struct{}
+
+func withOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationContextKey{}, operation)
+}
+
+// OperationName returns the name of the service method that is making the request, e.g. "akahu.Transactions.List".
+// It can be used by a Middleware with the context of the request it is given.
+func OperationName(ctx context.Context) string {
+	operation, _ := ctx.Value(operationContextKey{}).(string)
+	return operation
+}
+
+// chain wraps doer with the middleware, so that the first middleware is the outermost.
+func chain(doer Doer, middleware []Middleware) Doer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		doer = middleware[i](doer)
+	}
+
+	return doer
+}
+
+// RetryOptions configures RetryMiddleware.
+type RetryOptions struct {
+	// MaxAttempts is the total number of attempts, including the first. Defaults to 3.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, which doubles with each subsequent retry. Defaults to 500ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries, including any delay requested by a Retry-After header. Defaults to 10s.
+	MaxDelay time.Duration
+}
+
+// RetryMiddleware retries requests that fail with a transport error, or with a 429 or 5xx status code,
+// using exponential backoff with jitter. A Retry-After header sent by Akahu takes precedence over the backoff.
+//
+// Only requests with an idempotent method, or with an Idempotency-Key header, are retried.
+// The retried request is identical to the original, so payments and transfers are retried with the same key.
+func RetryMiddleware(opts RetryOptions) Middleware {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 500 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 10 * time.Second
+	}
+
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if !isRetryable(req) {
+				return next.Do(req)
+			}
+
+			for attempt := 1; ; attempt++ {
+				res, err := next.Do(req)
+				if attempt >= opts.MaxAttempts || !shouldRetry(res, err) {
+					return res, err
+				}
+
+				delay := opts.backoff(attempt, res)
+				if res != nil {
+					drainAndClose(res.Body)
+				}
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-req.Context().Done():
+					timer.Stop()
+					return nil, req.Context().Err()
+				case <-timer.C:
+				}
+
+				if req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, err
+					}
+					req = req.Clone(req.Context())
+					req.Body = body
+				}
+			}
+		})
+	}
+}
+
+func isRetryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if req.Header.Get(idempotencyKeyHeader) != "" {
+		return true
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
+
+func (o RetryOptions) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return minDuration(time.Duration(seconds)*time.Second, o.MaxDelay)
+		}
+	}
+
+	delay := minDuration(o.BaseDelay<<(attempt-1), o.MaxDelay)
+	// Full jitter spreads out retries from concurrent callers.
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// RateLimiter limits the rate of requests with a token bucket. It is safe for concurrent use, so one RateLimiter
+// can be shared by every request made by a Client, or by several Clients.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter that allows requestsPerSecond on average, and bursts of up to burst requests.
+// It panics if requestsPerSecond isn't positive.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if !(requestsPerSecond > 0) {
+		panic("akahu: non-positive rate for NewRateLimiter")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	// Take the token now, so that concurrent callers queue up behind each other rather than all waking at once.
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimitMiddleware waits for the limiter before each request, including each retry if it is listed after RetryMiddleware.
+func RateLimitMiddleware(limiter *RateLimiter) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if err := limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+
+			return next.Do(req)
+		})
+	}
+}
+
+// LoggingMiddleware logs the operation, method, path, status code and duration of each request.
+// Headers are never logged, as they contain the app secret and user access tokens.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.Do(req)
+			duration := time.Since(start)
+
+			operation := OperationName(req.Context())
+			if err != nil {
+				logger.Printf("akahu: %s %s %s failed after %s: %v", operation, req.Method, req.URL.Path, duration, err)
+			} else {
+				logger.Printf("akahu: %s %s %s %d in %s", operation, req.Method, req.URL.Path, res.StatusCode, duration)
+			}
+
+			return res, err
+		})
+	}
+}
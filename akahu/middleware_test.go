@@ -0,0 +1,178 @@
+package akahu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Middleware(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+OperationName(req.Context()))
+				return next.Do(req)
+			})
+		}
+	}
+
+	client := setupClient(t, fmt.Sprintf(collectionResponseJson, ""), http.MethodGet, http.StatusOK)
+	client.Middleware = []Middleware{record("outer"), record("inner")}
+
+	_, _, err := client.Accounts.List(context.TODO(), "user_token_1")
+	if err != nil {
+		t.Fatalf("client request returned err %v", err)
+	}
+
+	expected := []string{"outer akahu.Accounts.List", "inner akahu.Accounts.List"}
+	testClientResponse(t, expected, calls, nil)
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		statusCodes      []int
+		expectedAttempts int
+		expectedStatus   int
+	}{
+		{
+			name:             "with success on first attempt",
+			method:           http.MethodGet,
+			statusCodes:      []int{http.StatusOK},
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "with success after server errors",
+			method:           http.MethodGet,
+			statusCodes:      []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			expectedAttempts: 3,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:             "with attempts exhausted",
+			method:           http.MethodDelete,
+			statusCodes:      []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			expectedAttempts: 3,
+			expectedStatus:   http.StatusBadGateway,
+		},
+		{
+			name:             "with client error",
+			method:           http.MethodGet,
+			statusCodes:      []int{http.StatusBadRequest, http.StatusOK},
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusBadRequest,
+		},
+		{
+			name:             "with non idempotent method",
+			method:           http.MethodPost,
+			statusCodes:      []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var bodies []string
+			doer := DoerFunc(func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(body))
+
+				return &http.Response{
+					StatusCode: test.statusCodes[len(bodies)-1],
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			})
+
+			retry := RetryMiddleware(RetryOptions{BaseDelay: time.Millisecond})(doer)
+
+			req, _ := http.NewRequest(test.method, "https://api.akahu.io/v1/test", strings.NewReader("body"))
+			res, err := retry.Do(req)
+			if err != nil {
+				t.Fatalf("retry returned err %v", err)
+			}
+
+			if len(bodies) != test.expectedAttempts {
+				t.Fatalf("expected %d attempts, actual %d", test.expectedAttempts, len(bodies))
+			}
+
+			for _, body := range bodies {
+				if body != "body" {
+					t.Fatalf("expected request body %s on every attempt, actual %s", "body", body)
+				}
+			}
+
+			if res.StatusCode != test.expectedStatus {
+				t.Fatalf("expected status %d, actual %d", test.expectedStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRetryMiddleware_ContextCancelled(t *testing.T) {
+	doer := DoerFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Header:     http.Header{"Retry-After": []string{"60"}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.akahu.io/v1/test", nil)
+	_, err := RetryMiddleware(RetryOptions{MaxDelay: time.Minute})(doer).Do(req)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected err %v, actual %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	client := setupClient(t, fmt.Sprintf(collectionResponseJson, ""), http.MethodGet, http.StatusOK)
+	client.Middleware = []Middleware{RateLimitMiddleware(NewRateLimiter(100, 2))}
+
+	// The burst is allowed straight away, then each request waits 10ms for a token.
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, _, err := client.Accounts.List(context.TODO(), "user_token_1"); err != nil {
+			t.Fatalf("client request returned err %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("expected requests to be rate limited, took %s", elapsed)
+	}
+
+	limiter := NewRateLimiter(1, 1)
+	_ = limiter.Wait(context.TODO())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); err != context.Canceled {
+		t.Fatalf("expected err %v while waiting, actual %v", context.Canceled, err)
+	}
+}
+
+func TestNewRateLimiter_NonPositiveRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected NewRateLimiter to panic with rate %v", rate)
+				}
+			}()
+
+			NewRateLimiter(rate, 1)
+		}()
+	}
+}
@@ -0,0 +1,156 @@
+package akahu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultStateTTL = 10 * time.Minute
+
+var (
+	// ErrInvalidState is returned by OAuthFlow.HandleCallback when the callback's state is unknown, expired or already used.
+	ErrInvalidState = errors.New("akahu: invalid or expired OAuth state")
+	// ErrMissingCode is returned by OAuthFlow.HandleCallback when the callback has neither a code nor an error.
+	ErrMissingCode = errors.New("akahu: OAuth callback is missing the authorization code")
+)
+
+// OAuthError is an error returned to the redirect URI by Akahu, e.g. when the user denies access.
+//
+// See the Authorizing with OAuth 2.0 guide for the possible codes: https://developers.akahu.nz/docs/authorizing-with-oauth2.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("akahu: oauth error %s", e.Code)
+	}
+
+	return fmt.Sprintf("akahu: oauth error %s: %s", e.Code, e.Description)
+}
+
+// StateStore holds the OAuth states issued by OAuthFlow until the user returns to the redirect URI.
+type StateStore interface {
+	Save(ctx context.Context, state string, expiresAt time.Time) error
+	// Consume removes the state, returning false if it was never saved or has expired.
+	Consume(ctx context.Context, state string) (bool, error)
+}
+
+// MemoryStateStore is a StateStore that holds states in memory.
+// Use a shared StateStore if the callback may be handled by a different instance of your application.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]time.Time
+}
+
+// NewMemoryStateStore creates an empty MemoryStateStore.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: map[string]time.Time{}}
+}
+
+func (s *MemoryStateStore) Save(_ context.Context, state string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for st, exp := range s.states {
+		if now.After(exp) {
+			delete(s.states, st)
+		}
+	}
+
+	s.states[state] = expiresAt
+	return nil
+}
+
+func (s *MemoryStateStore) Consume(_ context.Context, state string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.states[state]
+	delete(s.states, state)
+
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// OAuthFlow runs the OAuth authorization flow, protecting it against CSRF with a random, single use state.
+type OAuthFlow struct {
+	client *Client
+	states StateStore
+
+	// StateTTL is how long the user has to complete authorization. Defaults to 10 minutes.
+	StateTTL time.Duration
+}
+
+// NewOAuthFlow creates an OAuthFlow for the client, saving states to states.
+func NewOAuthFlow(client *Client, states StateStore) *OAuthFlow {
+	return &OAuthFlow{
+		client:   client,
+		states:   states,
+		StateTTL: defaultStateTTL,
+	}
+}
+
+// AuthorizationURL generates and saves a new state, then builds the URL to redirect the user to.
+// Any State set in options is replaced.
+func (f *OAuthFlow) AuthorizationURL(ctx context.Context, options AuthorizationURLOptions) (string, string, error) {
+	state, err := newState()
+	if err != nil {
+		return "", "", err
+	}
+	options.State = &state
+
+	authURL, err := f.client.Auth.BuildAuthorizationURL(options)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := f.states.Save(ctx, state, time.Now().Add(f.StateTTL)); err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// HandleCallback handles the request made to the redirect URI once the user has completed authorization.
+// It verifies the state, then exchanges the code for a user access token.
+//
+// An *OAuthError is returned if Akahu redirected with an error, and ErrInvalidState if the state can't be verified.
+func (f *OAuthFlow) HandleCallback(ctx context.Context, r *http.Request) (*ExchangeResponse, *APIResponse, error) {
+	query := r.URL.Query()
+
+	valid, err := f.states.Consume(ctx, query.Get("state"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		return nil, nil, ErrInvalidState
+	}
+
+	if code := query.Get("error"); code != "" {
+		return nil, nil, &OAuthError{Code: code, Description: query.Get("error_description")}
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return nil, nil, ErrMissingCode
+	}
+
+	return f.client.Auth.Exchange(ctx, code)
+}
+
+func newState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,98 @@
+package akahu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestOAuthFlow(t *testing.T) {
+	tests := []struct {
+		name        string
+		callback    func(state string) url.Values
+		expected    *ExchangeResponse
+		expectedErr error
+	}{
+		{
+			name: "with valid state and code",
+			callback: func(state string) url.Values {
+				return url.Values{"state": {state}, "code": {"code_1"}}
+			},
+			expected: &ExchangeResponse{
+				AccessToken: "user_token_1111111111111111111111111",
+				TokenType:   "bearer",
+				Scope:       "IDENTITY_BASIC ACCOUNTS TRANSACTIONS",
+			},
+		},
+		{
+			name: "with unknown state",
+			callback: func(state string) url.Values {
+				return url.Values{"state": {"forged"}, "code": {"code_1"}}
+			},
+			expectedErr: ErrInvalidState,
+		},
+		{
+			name: "with missing code",
+			callback: func(state string) url.Values {
+				return url.Values{"state": {state}}
+			},
+			expectedErr: ErrMissingCode,
+		},
+		{
+			name: "with oauth error",
+			callback: func(state string) url.Values {
+				return url.Values{"state": {state}, "error": {"access_denied"}, "error_description": {"The user denied access"}}
+			},
+			expectedErr: &OAuthError{Code: "access_denied", Description: "The user denied access"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setupClient(t, exchangeJson, http.MethodPost, http.StatusOK)
+			flow := NewOAuthFlow(client, NewMemoryStateStore())
+
+			authURL, state, err := flow.AuthorizationURL(context.TODO(), AuthorizationURLOptions{})
+			if err != nil {
+				t.Fatalf("AuthorizationURL returned err %v", err)
+			}
+
+			parsed, _ := url.Parse(authURL)
+			if actual := parsed.Query().Get("state"); actual != state || len(state) < 32 {
+				t.Fatalf("expected state %s in authorization URL, actual %s", state, actual)
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, "https://example.com/auth/akahu?"+test.callback(state).Encode(), nil)
+			actual, _, err := flow.HandleCallback(context.TODO(), req)
+
+			if test.expectedErr != nil {
+				var oauthErr *OAuthError
+				if errors.As(test.expectedErr, &oauthErr) {
+					testClientResponse(t, test.expectedErr, err, nil)
+				} else if err != test.expectedErr {
+					t.Fatalf("expected err %v, actual %v", test.expectedErr, err)
+				}
+				return
+			}
+
+			testClientResponse(t, test.expected, actual, err)
+
+			// A state can only be used once.
+			if _, _, err := flow.HandleCallback(context.TODO(), req); err != ErrInvalidState {
+				t.Fatalf("expected err %v on replayed callback, actual %v", ErrInvalidState, err)
+			}
+		})
+	}
+}
+
+func TestMemoryStateStore_Expiry(t *testing.T) {
+	store := NewMemoryStateStore()
+	_ = store.Save(context.TODO(), "expired", time.Now().Add(-time.Second))
+
+	if valid, _ := store.Consume(context.TODO(), "expired"); valid {
+		t.Fatalf("expected expired state to be invalid")
+	}
+}
@@ -0,0 +1,61 @@
+package akahu
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
+
+const paymentsPath = "payments"
+
+type PaymentsService service
+
+type PaymentRecipient struct {
+	Name          string `json:"name"`
+	AccountNumber string `json:"account_number"`
+}
+
+type PaymentSourceMeta struct {
+	Code      *string `json:"code,omitempty"`
+	Reference *string `json:"reference,omitempty"`
+}
+
+type PaymentDestinationMeta struct {
+	Particulars *string `json:"particulars,omitempty"`
+	Code        *string `json:"code,omitempty"`
+	Reference   *string `json:"reference,omitempty"`
+}
+
+type PaymentMeta struct {
+	Source      *PaymentSourceMeta      `json:"source,omitempty"`
+	Destination *PaymentDestinationMeta `json:"destination,omitempty"`
+}
+
+type PaymentRequest struct {
+	From   AccountID        `json:"from"`
+	Amount decimal.Decimal  `json:"amount"`
+	To     PaymentRecipient `json:"to"`
+	Meta   *PaymentMeta     `json:"meta,omitempty"`
+}
+
+// MarshalJSON encodes Amount as a JSON number, as expected by Akahu.
+func (r PaymentRequest) MarshalJSON() ([]byte, error) {
+	type alias PaymentRequest
+	return json.Marshal(struct {
+		alias
+		Amount json.Number `json:"amount"`
+	}{alias(r), json.Number(r.Amount.String())})
+}
+
+// Create initiates a payment from one of the user's connected accounts to a bank account, returning the ID of the payment.
+// An Idempotency-Key header is always sent, see IdempotencyOptions for how it is chosen.
+//
+// Akahu docs: https://developers.akahu.nz/reference/post_payments
+func (s *PaymentsService) Create(ctx context.Context, userAccessToken string, body PaymentRequest, opts IdempotencyOptions) (*string, *APIResponse, error) {
+	if err := checkID("from", body.From); err != nil {
+		return nil, nil, err
+	}
+
+	return s.client.createIdempotent(ctx, "akahu.Payments.Create", paymentsPath, userAccessToken, body, opts)
+}
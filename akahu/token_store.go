@@ -0,0 +1,161 @@
+package akahu
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrTokenNotFound is returned by a TokenStore when there is no access token stored for a user.
+var ErrTokenNotFound = errors.New("akahu: no access token stored for user")
+
+// TokenStore holds the access tokens of your application's users, keyed by their Akahu user ID.
+type TokenStore interface {
+	// Get returns the user's access token, or ErrTokenNotFound.
+	Get(ctx context.Context, userID string) (string, error)
+	Put(ctx context.Context, userID, userAccessToken string) error
+	Delete(ctx context.Context, userID string) error
+	// DeleteToken removes an access token, whichever user it belongs to.
+	DeleteToken(ctx context.Context, userAccessToken string) error
+	// UserIDs lists the users that have an access token stored.
+	UserIDs(ctx context.Context) ([]string, error)
+}
+
+// MemoryTokenStore is a TokenStore that holds tokens in memory.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// NewMemoryTokenStore creates an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[string]string{}}
+}
+
+func (s *MemoryTokenStore) Get(_ context.Context, userID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[userID]
+	if !ok {
+		return "", ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (s *MemoryTokenStore) Put(_ context.Context, userID, userAccessToken string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[userID] = userAccessToken
+	return nil
+}
+
+func (s *MemoryTokenStore) Delete(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, userID)
+	return nil
+}
+
+func (s *MemoryTokenStore) DeleteToken(_ context.Context, userAccessToken string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleteTokenValue(s.tokens, userAccessToken)
+	return nil
+}
+
+func (s *MemoryTokenStore) UserIDs(_ context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedKeys(s.tokens), nil
+}
+
+// FileTokenStore is a TokenStore that persists tokens to a JSON file, readable only by the current user.
+// Tokens are stored in plaintext, see EncryptedTokenStore if they must be encrypted at rest.
+type FileTokenStore struct {
+	file jsonFileMap
+}
+
+// NewFileTokenStore creates a FileTokenStore backed by the file at path, which is created if it doesn't exist.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{file: jsonFileMap{path: path}}
+}
+
+func (s *FileTokenStore) Get(_ context.Context, userID string) (string, error) {
+	tokens, err := s.file.read()
+	if err != nil {
+		return "", err
+	}
+
+	token, ok := tokens[userID]
+	if !ok {
+		return "", ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (s *FileTokenStore) Put(_ context.Context, userID, userAccessToken string) error {
+	return s.file.update(func(tokens map[string]string) error {
+		tokens[userID] = userAccessToken
+		return nil
+	})
+}
+
+func (s *FileTokenStore) Delete(_ context.Context, userID string) error {
+	return s.file.update(func(tokens map[string]string) error {
+		delete(tokens, userID)
+		return nil
+	})
+}
+
+func (s *FileTokenStore) DeleteToken(_ context.Context, userAccessToken string) error {
+	return s.file.update(func(tokens map[string]string) error {
+		deleteTokenValue(tokens, userAccessToken)
+		return nil
+	})
+}
+
+func (s *FileTokenStore) UserIDs(_ context.Context) ([]string, error) {
+	tokens, err := s.file.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedKeys(tokens), nil
+}
+
+func deleteTokenValue(tokens map[string]string, userAccessToken string) {
+	for userID, token := range tokens {
+		if token == userAccessToken {
+			delete(tokens, userID)
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// EvictRevokedToken removes a user's access token from Client.Tokens when payload is a TOKEN webhook reporting
+// that the token has been revoked. Akahu identifies the user by the state given when subscribing to the webhook,
+// which UserClient.Webhooks.Subscribe sets to the user ID when the UserClient was created with ForUserID.
+func (c *Client) EvictRevokedToken(ctx context.Context, payload WebHookEventPayload) error {
+	if payload.WebhookType != Token || payload.WebhookCode != "DELETE" || payload.State == "" {
+		return nil
+	}
+
+	return c.Tokens.Delete(ctx, payload.State)
+}
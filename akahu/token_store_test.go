@@ -0,0 +1,150 @@
+package akahu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestTokenStores(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) TokenStore
+	}{
+		{
+			name: "with memory store",
+			store: func(t *testing.T) TokenStore {
+				return NewMemoryTokenStore()
+			},
+		},
+		{
+			name: "with file store",
+			store: func(t *testing.T) TokenStore {
+				return NewFileTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.TODO()
+			store := test.store(t)
+
+			if _, err := store.Get(ctx, "user_1"); err != ErrTokenNotFound {
+				t.Fatalf("expected err %v, actual %v", ErrTokenNotFound, err)
+			}
+
+			_ = store.Put(ctx, "user_1", "token_1")
+			_ = store.Put(ctx, "user_2", "token_2")
+			_ = store.Put(ctx, "user_3", "token_3")
+
+			token, err := store.Get(ctx, "user_2")
+			testClientResponse(t, "token_2", token, err)
+
+			_ = store.DeleteToken(ctx, "token_2")
+			_ = store.Delete(ctx, "user_3")
+
+			userIDs, err := store.UserIDs(ctx)
+			testClientResponse(t, []string{"user_1"}, userIDs, err)
+		})
+	}
+}
+
+func TestClient_ForUserID(t *testing.T) {
+	client := setupClient(t, fmt.Sprintf(collectionResponseJson, ""), http.MethodGet, http.StatusOK, func(r *http.Request) {
+		testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
+	})
+
+	if _, err := client.ForUserID(context.TODO(), "user_1"); err != ErrTokenNotFound {
+		t.Fatalf("expected err %v, actual %v", ErrTokenNotFound, err)
+	}
+
+	_ = client.Tokens.Put(context.TODO(), "user_1", "user_token_1")
+
+	user, err := client.ForUserID(context.TODO(), "user_1")
+	if err != nil {
+		t.Fatalf("ForUserID returned err %v", err)
+	}
+
+	actual, res, err := user.Accounts.List(context.TODO())
+	testClientResponse(t, []AccountResponse{}, actual, err)
+	testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+}
+
+func TestAuthService_RevokeToken_EvictsToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		jsonResponse  string
+		statusCode    int
+		expectedCount int
+	}{
+		{
+			name:          "with success response",
+			jsonResponse:  "{ \"success\": true }",
+			statusCode:    http.StatusOK,
+			expectedCount: 0,
+		},
+		{
+			name:          "with error response",
+			jsonResponse:  errorResponseJsonWithMessage,
+			statusCode:    http.StatusBadRequest,
+			expectedCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setupClient(t, test.jsonResponse, http.MethodDelete, test.statusCode, func(r *http.Request) {
+				testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
+			})
+			_ = client.Tokens.Put(context.TODO(), "user_1", "user_token_1")
+
+			_, _, err := client.ForUser("user_token_1").Auth.RevokeToken(context.TODO())
+			if err != nil {
+				t.Fatalf("client request returned err %v", err)
+			}
+
+			userIDs, _ := client.Tokens.UserIDs(context.TODO())
+			if len(userIDs) != test.expectedCount {
+				t.Fatalf("expected %d stored tokens, actual %d", test.expectedCount, len(userIDs))
+			}
+		})
+	}
+}
+
+func TestClient_EvictRevokedToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       WebHookEventPayload
+		expectedCount int
+	}{
+		{
+			name:          "with token delete webhook",
+			payload:       WebHookEventPayload{WebhookType: Token, WebhookCode: "DELETE", State: "user_1"},
+			expectedCount: 0,
+		},
+		{
+			name:          "with other webhook",
+			payload:       WebHookEventPayload{WebhookType: Transaction, WebhookCode: "DELETE", State: "user_1"},
+			expectedCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewClient(nil, "app_token_123", "appSecret123", "")
+			_ = client.Tokens.Put(context.TODO(), "user_1", "user_token_1")
+
+			if err := client.EvictRevokedToken(context.TODO(), test.payload); err != nil {
+				t.Fatalf("EvictRevokedToken returned err %v", err)
+			}
+
+			userIDs, _ := client.Tokens.UserIDs(context.TODO())
+			if len(userIDs) != test.expectedCount {
+				t.Fatalf("expected %d stored tokens, actual %d", test.expectedCount, len(userIDs))
+			}
+		})
+	}
+}
@@ -2,8 +2,9 @@ package akahu
 
 import (
 	"context"
+	"fmt"
 	"net/http"
-	"path"
+	"sync"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -12,6 +13,14 @@ import (
 const transactionsPath = "transactions"
 const pendingPath = "pending"
 
+const (
+	// defaultGetByIdsBatchSize is the most ids sent in one request to the transactions/ids endpoint, unless
+	// Client.TransactionIdsBatchSize is set.
+	defaultGetByIdsBatchSize = 100
+	// getByIdsWorkers is the most requests GetByIds makes at once.
+	getByIdsWorkers = 4
+)
+
 type TransactionsService service
 
 type Merchant struct {
@@ -50,9 +59,9 @@ type Meta struct {
 }
 
 type TransactionResponse struct {
-	Id          string          `json:"_id"`
-	Account     string          `json:"_account"`
-	Connection  string          `json:"_connection"`
+	Id          TransactionID   `json:"_id"`
+	Account     AccountID       `json:"_account"`
+	Connection  ConnectionID    `json:"_connection"`
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 	Date        time.Time       `json:"date"`
@@ -65,32 +74,59 @@ type TransactionResponse struct {
 	Meta        *Meta           `json:"meta"`
 }
 
-// List gets a list of settled transactions within the 'start' and 'end' time range.
+// List gets a list of settled transactions within the 'start' and 'end' time range, fetching every page.
 //
 // Akahu docs: https://developers.akahu.nz/reference/get_transactions
 func (s *TransactionsService) List(ctx context.Context, userAccessToken string, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
-	return s.list(ctx, transactionsPath, userAccessToken, startTime, endTime)
+	return s.list(ctx, "akahu.Transactions.List", transactionsPath, userAccessToken, startTime, endTime)
 }
 
 // ListPending gets a list of pending transactions within the 'start' and 'end' time range.
 //
 // Akahu docs: https://developers.akahu.nz/reference/get_transactions-pending
 func (s *TransactionsService) ListPending(ctx context.Context, userAccessToken string, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
-	return s.list(ctx, path.Join(transactionsPath, pendingPath), userAccessToken, startTime, endTime)
+	return s.list(ctx, "akahu.Transactions.ListPending", joinPath(transactionsPath, pendingPath), userAccessToken, startTime, endTime)
+}
+
+// ListByAccount gets a list of settled transactions for one of the user's connected accounts within the 'start' and 'end' time range,
+// fetching every page.
+//
+// Akahu docs: https://developers.akahu.nz/reference/get_accounts-id-transactions
+func (s *TransactionsService) ListByAccount(ctx context.Context, userAccessToken string, accountID AccountID, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
+	if err := checkID("accountID", accountID); err != nil {
+		return nil, nil, err
+	}
+
+	return s.list(ctx, "akahu.Transactions.ListByAccount", joinPath(accountsPath, string(accountID), transactionsPath), userAccessToken, startTime, endTime)
+}
+
+// ListPendingByAccount gets a list of pending transactions for one of the user's connected accounts within the 'start' and 'end' time range.
+//
+// Akahu docs: https://developers.akahu.nz/reference/get_accounts-id-transactions-pending
+func (s *TransactionsService) ListPendingByAccount(ctx context.Context, userAccessToken string, accountID AccountID, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
+	if err := checkID("accountID", accountID); err != nil {
+		return nil, nil, err
+	}
+
+	return s.list(ctx, "akahu.Transactions.ListPendingByAccount", joinPath(accountsPath, string(accountID), transactionsPath, pendingPath), userAccessToken, startTime, endTime)
 }
 
 // Get fetches an individual transaction from one of the user's connected accounts.
 // All returned dates are in UTC.
 //
 // Akahu docs: https://developers.akahu.nz/reference/get_transactions-id
-func (s *TransactionsService) Get(ctx context.Context, userAccessToken, id string) (*TransactionResponse, *APIResponse, error) {
-	r, err := s.client.newRequest(http.MethodGet, path.Join(transactionsPath, id), nil, withTokenRequestConfig(userAccessToken))
+func (s *TransactionsService) Get(ctx context.Context, userAccessToken string, id TransactionID) (*TransactionResponse, *APIResponse, error) {
+	if err := checkID("transactionID", id); err != nil {
+		return nil, nil, err
+	}
+
+	r, err := s.client.newRequest(http.MethodGet, joinPath(transactionsPath, string(id)), nil, withTokenRequestConfig(userAccessToken))
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var accounts itemResponse[TransactionResponse]
-	res, err := s.client.do(ctx, r, &accounts)
+	res, err := s.client.do(ctx, "akahu.Transactions.Get", r, &accounts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -101,36 +137,213 @@ func (s *TransactionsService) Get(ctx context.Context, userAccessToken, id strin
 // GetByIds fetches a list of transactions from one of the user's connected accounts by Transaction ids.
 // All returned dates are in UTC.
 //
+// Large lists of ids are split into batches that are fetched concurrently. Transactions are returned in the order
+// of ids, and ids that weren't found are left out; use LookupByIds to find out which.
+//
 // Akahu docs: https://developers.akahu.nz/reference/post_transactions-ids
-func (s *TransactionsService) GetByIds(ctx context.Context, userAccessToken string, ids ...string) ([]TransactionResponse, *APIResponse, error) {
-	r, err := s.client.newRequest(http.MethodPost, path.Join(transactionsPath, "ids"), ids, withTokenRequestConfig(userAccessToken))
+func (s *TransactionsService) GetByIds(ctx context.Context, userAccessToken string, ids ...TransactionID) ([]TransactionResponse, *APIResponse, error) {
+	lookup, res, err := s.LookupByIds(ctx, userAccessToken, ids...)
+	if err != nil || lookup == nil {
+		return nil, res, err
+	}
+
+	return lookup.Found, res, nil
+}
+
+// TransactionLookup is the result of TransactionsService.LookupByIds.
+type TransactionLookup struct {
+	// Found are the transactions that were found, in the order their ids were given.
+	Found []TransactionResponse
+	// NotFound are the ids that no transaction was returned for.
+	NotFound []TransactionID
+}
+
+// LookupByIds fetches transactions like GetByIds, but also reports the ids that weren't found.
+//
+// Batches of Client.TransactionIdsBatchSize ids are fetched by a limited number of concurrent requests. If any request
+// fails, the rest are cancelled and the response or error of the request that failed first is returned with a nil
+// TransactionLookup.
+//
+// With no ids, an empty TransactionLookup is returned without making a request, along with a successful APIResponse
+// that has no HTTP response.
+func (s *TransactionsService) LookupByIds(ctx context.Context, userAccessToken string, ids ...TransactionID) (*TransactionLookup, *APIResponse, error) {
+	for i, id := range ids {
+		if err := checkID(fmt.Sprintf("ids[%d]", i), id); err != nil {
+			return nil, nil, err
+		}
+	}
+	ids = uniqueIds(ids)
+	if len(ids) == 0 {
+		return &TransactionLookup{Found: []TransactionResponse{}}, &APIResponse{Success: true}, nil
+	}
+
+	batchSize := s.client.TransactionIdsBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultGetByIdsBatchSize
+	}
+
+	var batches [][]TransactionID
+	remaining := ids
+	for len(remaining) > batchSize {
+		batches = append(batches, remaining[:batchSize])
+		remaining = remaining[batchSize:]
+	}
+	batches = append(batches, remaining)
+
+	results := make([][]TransactionResponse, len(batches))
+	responses := make([]*APIResponse, len(batches))
+	errs := make([]error, len(batches))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// failed is the batch whose request failed first, which caused the others to be cancelled.
+	var failOnce sync.Once
+	failed := -1
+	fetch := func(i int) {
+		results[i], responses[i], errs[i] = s.getByIds(ctx, userAccessToken, batches[i])
+		if errs[i] != nil || !responses[i].Success {
+			failOnce.Do(func() {
+				failed = i
+				cancel()
+			})
+		}
+	}
+
+	if len(batches) == 1 {
+		fetch(0)
+	} else {
+		var wg sync.WaitGroup
+		workers := make(chan struct{}, getByIdsWorkers)
+		for i := range batches {
+			i := i
+
+			wg.Add(1)
+			workers <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-workers }()
+
+				fetch(i)
+			}()
+		}
+		wg.Wait()
+	}
+
+	if failed >= 0 {
+		if errs[failed] != nil {
+			return nil, nil, errs[failed]
+		}
+		return nil, responses[failed], nil
+	}
+
+	byId := map[TransactionID]TransactionResponse{}
+	lookup := &TransactionLookup{Found: []TransactionResponse{}}
+	for _, batch := range results {
+		for _, t := range batch {
+			if _, ok := byId[t.Id]; !ok {
+				byId[t.Id] = t
+			}
+		}
+	}
+
+	requested := map[TransactionID]bool{}
+	for _, id := range ids {
+		requested[id] = true
+		if t, ok := byId[id]; ok {
+			lookup.Found = append(lookup.Found, t)
+		} else {
+			lookup.NotFound = append(lookup.NotFound, id)
+		}
+	}
+
+	// Keep any transactions returned for ids that weren't asked for, rather than silently dropping them.
+	for _, batch := range results {
+		for _, t := range batch {
+			if !requested[t.Id] {
+				requested[t.Id] = true
+				lookup.Found = append(lookup.Found, t)
+			}
+		}
+	}
+
+	return lookup, responses[len(responses)-1], nil
+}
+
+func (s *TransactionsService) getByIds(ctx context.Context, userAccessToken string, ids []TransactionID) ([]TransactionResponse, *APIResponse, error) {
+	r, err := s.client.newRequest(http.MethodPost, joinPath(transactionsPath, "ids"), ids, withTokenRequestConfig(userAccessToken))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	var accounts collectionResponse[TransactionResponse]
-	res, err := s.client.do(ctx, r, &accounts)
+	var transactions collectionResponse[TransactionResponse]
+	res, err := s.client.do(ctx, "akahu.Transactions.GetByIds", r, &transactions)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return accounts.Items, res, nil
+	return transactions.Items, res, nil
 }
 
-func (s *TransactionsService) list(ctx context.Context, urlPath, userAccessToken string, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
-	params := paramsWithDateRange(startTime, endTime)
-	encodedPath := pathWithParams(urlPath, params)
+// uniqueIds removes repeated ids, keeping the first of each.
+func uniqueIds(ids []TransactionID) []TransactionID {
+	if len(ids) < 2 {
+		return ids
+	}
 
-	r, err := s.client.newRequest(http.MethodGet, encodedPath, nil, withTokenRequestConfig(userAccessToken))
-	if err != nil {
-		return nil, nil, err
+	seen := map[TransactionID]bool{}
+	unique := make([]TransactionID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
 	}
 
-	var accounts collectionResponse[TransactionResponse]
-	res, err := s.client.do(ctx, r, &accounts)
+	return unique
+}
+
+// list gets every page of transactions at urlPath, following the cursor until Akahu returns no next page. If any
+// page is unsuccessful its response is returned without transactions, so callers never see a partial list.
+func (s *TransactionsService) list(ctx context.Context, operation, urlPath, userAccessToken string, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
+	params, err := paramsWithDateRange(startTime, endTime)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return accounts.Items, res, nil
+	var (
+		transactions []TransactionResponse
+		cursor       string
+	)
+	for {
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		encodedPath := pathWithParams(urlPath, params)
+
+		r, err := s.client.newRequest(http.MethodGet, encodedPath, nil, withTokenRequestConfig(userAccessToken))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var page collectionResponse[TransactionResponse]
+		res, err := s.client.do(ctx, operation, r, &page)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !res.Success {
+			return nil, res, nil
+		}
+
+		if transactions == nil {
+			transactions = page.Items
+		} else {
+			transactions = append(transactions, page.Items...)
+		}
+
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			return transactions, res, nil
+		}
+		cursor = res.NextCursor
+	}
 }
@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -96,7 +100,7 @@ func TestTransactionsService_Get(t *testing.T) {
 				testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
 			})
 
-			actual, res, err := client.Transactions.Get(context.TODO(), "user_token_1", "id_1")
+			actual, res, err := client.Transactions.Get(context.TODO(), "user_token_1", "trans_1")
 			testClientResponse(t, test.expected, actual, err)
 			testClientAPIResponse(t, test.expectedAPIResponse, res, err)
 		})
@@ -107,7 +111,7 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 	tests := []struct {
 		name                string
 		jsonResponse        string
-		ids                 []string
+		ids                 []TransactionID
 		statusCode          int
 		expected            []TransactionResponse
 		expectedAPIResponse *APIResponse
@@ -115,7 +119,7 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 		{
 			name:                "with empty response",
 			jsonResponse:        fmt.Sprintf(collectionResponseJson, ""),
-			ids:                 []string{"id_1"},
+			ids:                 []TransactionID{"trans_1"},
 			statusCode:          http.StatusOK,
 			expected:            []TransactionResponse{},
 			expectedAPIResponse: expectedSuccessAPIResponse,
@@ -123,7 +127,7 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 		{
 			name:                "with multiple ids",
 			jsonResponse:        fmt.Sprintf(collectionResponseJson, ""),
-			ids:                 []string{"id_1", "id_2", "id_3"},
+			ids:                 []TransactionID{"trans_1", "trans_2", "trans_3"},
 			statusCode:          http.StatusOK,
 			expected:            []TransactionResponse{},
 			expectedAPIResponse: expectedSuccessAPIResponse,
@@ -131,7 +135,7 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 		{
 			name:         "with single unenriched response",
 			jsonResponse: fmt.Sprintf(collectionResponseJson, unenrichedTransactionJson),
-			ids:          []string{"id_1"},
+			ids:          []TransactionID{"trans_1"},
 			statusCode:   http.StatusOK,
 			expected: []TransactionResponse{
 				{
@@ -152,7 +156,7 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 		{
 			name:         "with single enriched response",
 			jsonResponse: fmt.Sprintf(collectionResponseJson, enrichedTransactionJson),
-			ids:          []string{"id_1"},
+			ids:          []TransactionID{"trans_1"},
 			statusCode:   http.StatusOK,
 			expected: []TransactionResponse{
 				{
@@ -187,6 +191,7 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 		{
 			name:                "with error response",
 			jsonResponse:        errorResponseJsonWithMessage,
+			ids:                 []TransactionID{"trans_1"},
 			statusCode:          http.StatusBadRequest,
 			expected:            nil,
 			expectedAPIResponse: expectedErrorAPIResponse,
@@ -198,7 +203,7 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 			client := setupClient(t, test.jsonResponse, http.MethodPost, test.statusCode, func(r *http.Request) {
 				testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
 
-				var ids []string
+				var ids []TransactionID
 				_ = json.NewDecoder(r.Body).Decode(&ids)
 
 				if !reflect.DeepEqual(ids, test.ids) {
@@ -213,6 +218,151 @@ func TestTransactionsService_GetByIds(t *testing.T) {
 	}
 }
 
+func TestTransactionsService_LookupByIds(t *testing.T) {
+	var ids []TransactionID
+	for i := 0; i < 250; i++ {
+		ids = append(ids, TransactionID(fmt.Sprintf("trans_%03d", i)))
+	}
+
+	tests := []struct {
+		name                string
+		batchSize           int
+		failBatch           string
+		expectedBatchSizes  []int
+		expectedNotFound    []TransactionID
+		expectedAPIResponse *APIResponse
+	}{
+		{
+			name:                "with ids across batches",
+			expectedBatchSizes:  []int{50, 100, 100},
+			expectedNotFound:    []TransactionID{"trans_007", "trans_249"},
+			expectedAPIResponse: expectedSuccessAPIResponse,
+		},
+		{
+			name:                "with batch size",
+			batchSize:           60,
+			expectedBatchSizes:  []int{10, 60, 60, 60, 60},
+			expectedNotFound:    []TransactionID{"trans_007", "trans_249"},
+			expectedAPIResponse: expectedSuccessAPIResponse,
+		},
+		{
+			name:                "with failed batch",
+			failBatch:           "trans_100",
+			expectedAPIResponse: expectedErrorAPIResponse,
+		},
+		{
+			name:                "with failed first batch",
+			failBatch:           "trans_000",
+			expectedAPIResponse: expectedErrorAPIResponse,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				mu                sync.Mutex
+				batchSizes        []int
+				inFlight, maxSeen int
+			)
+
+			mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				inFlight++
+				if inFlight > maxSeen {
+					maxSeen = inFlight
+				}
+				mu.Unlock()
+				defer func() {
+					mu.Lock()
+					inFlight--
+					mu.Unlock()
+				}()
+				time.Sleep(5 * time.Millisecond)
+
+				var requested []string
+				_ = json.NewDecoder(req.Body).Decode(&requested)
+
+				mu.Lock()
+				batchSizes = append(batchSizes, len(requested))
+				mu.Unlock()
+
+				if requested[0] == test.failBatch {
+					return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(errorResponseJsonWithMessage))}, nil
+				}
+
+				// Return the found transactions out of order, as the API doesn't guarantee it.
+				var items []string
+				for i := len(requested) - 1; i >= 0; i-- {
+					if requested[i] != "trans_007" && requested[i] != "trans_249" {
+						items = append(items, fmt.Sprintf(`{ "_id": "%s", "amount": -1, "balance": 0 }`, requested[i]))
+					}
+				}
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(collectionResponseJson, strings.Join(items, ",")))),
+				}, nil
+			})}
+			client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+			client.TransactionIdsBatchSize = test.batchSize
+
+			// Repeated ids are only requested once.
+			actual, res, err := client.Transactions.LookupByIds(context.TODO(), "user_token_1", append(ids, "trans_000")...)
+			testClientAPIResponse(t, test.expectedAPIResponse, res, err)
+
+			if maxSeen > getByIdsWorkers {
+				t.Fatalf("expected at most %d concurrent requests, actual %d", getByIdsWorkers, maxSeen)
+			}
+
+			if test.failBatch != "" {
+				if actual != nil {
+					t.Fatalf("expected nil lookup, actual %+v", actual)
+				}
+				return
+			}
+
+			sort.Ints(batchSizes)
+			if !reflect.DeepEqual(batchSizes, test.expectedBatchSizes) {
+				t.Fatalf("expected batches of %v, actual %v", test.expectedBatchSizes, batchSizes)
+			}
+			if !reflect.DeepEqual(actual.NotFound, test.expectedNotFound) {
+				t.Fatalf("expected not found %v, actual %v", test.expectedNotFound, actual.NotFound)
+			}
+			if len(actual.Found) != 248 {
+				t.Fatalf("expected 248 transactions, actual %d", len(actual.Found))
+			}
+			for i, transaction := range actual.Found {
+				if i > 0 && transaction.Id <= actual.Found[i-1].Id {
+					t.Fatalf("expected transactions in input order, actual %s after %s", transaction.Id, actual.Found[i-1].Id)
+				}
+			}
+		})
+	}
+}
+
+func TestTransactionsService_LookupByIds_NoIds(t *testing.T) {
+	requests := 0
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(fmt.Sprintf(collectionResponseJson, "")))}, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+	actual, res, err := client.Transactions.LookupByIds(context.TODO(), "user_token_1")
+	if err != nil || !res.Success {
+		t.Fatalf("expected successful response, actual %+v %v", res, err)
+	}
+	if len(actual.Found) != 0 || len(actual.NotFound) != 0 {
+		t.Fatalf("expected empty lookup, actual %+v", actual)
+	}
+
+	transactions, _, _ := client.Transactions.GetByIds(context.TODO(), "user_token_1")
+	testClientResponse(t, []TransactionResponse{}, transactions, nil)
+	if requests != 0 {
+		t.Fatalf("expected no requests, actual %d", requests)
+	}
+}
+
 func TestTransactionsService_List(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -304,6 +454,31 @@ func TestTransactionsService_List(t *testing.T) {
 	}
 }
 
+func TestTransactionsService_List_Pages(t *testing.T) {
+	var cursors []string
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		cursor := req.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+
+		body := `{ "success": true, "items": [{ "_id": "trans_1" }], "cursor": { "next": "page_2" } }`
+		if cursor == "page_2" {
+			body = `{ "success": true, "items": [{ "_id": "trans_2" }], "cursor": { "next": null } }`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+	transactions, res, err := client.Transactions.List(context.TODO(), "user_token_1", time.Now().Add(-time.Hour), time.Now())
+	testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+
+	if len(transactions) != 2 || transactions[0].Id != "trans_1" || transactions[1].Id != "trans_2" {
+		t.Fatalf("expected transactions from both pages, actual %+v", transactions)
+	}
+	if !reflect.DeepEqual(cursors, []string{"", "page_2"}) {
+		t.Fatalf("expected cursors %v, actual %v", []string{"", "page_2"}, cursors)
+	}
+}
+
 func TestTransactionsService_ListPending(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -394,3 +569,48 @@ func TestTransactionsService_ListPending(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionsService_ListByAccount(t *testing.T) {
+	tests := []struct {
+		name         string
+		pending      bool
+		expectedPath string
+	}{
+		{
+			name:         "with settled transactions",
+			pending:      false,
+			expectedPath: "/v1/accounts/acc_1111111111111111111111111/transactions",
+		},
+		{
+			name:         "with pending transactions",
+			pending:      true,
+			expectedPath: "/v1/accounts/acc_1111111111111111111111111/transactions/pending",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := setupClient(t, fmt.Sprintf(collectionResponseJson, unenrichedTransactionJson), http.MethodGet, http.StatusOK, func(r *http.Request) {
+				testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
+
+				if r.URL.Path != test.expectedPath {
+					t.Fatalf("expected path %s, actual %s", test.expectedPath, r.URL.Path)
+				}
+			})
+
+			list := client.Transactions.ListByAccount
+			if test.pending {
+				list = client.Transactions.ListPendingByAccount
+			}
+
+			actual, res, err := list(context.TODO(), "user_token_1", "acc_1111111111111111111111111", time.Now(), time.Now())
+			if err != nil {
+				t.Fatalf("client request returned err %v", err)
+			}
+			if len(actual) != 1 {
+				t.Fatalf("expected 1 transaction, actual %d", len(actual))
+			}
+			testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+		})
+	}
+}
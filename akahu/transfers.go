@@ -0,0 +1,42 @@
+package akahu
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
+
+const transfersPath = "transfers"
+
+type TransfersService service
+
+type TransferRequest struct {
+	From   AccountID       `json:"from"`
+	To     AccountID       `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// MarshalJSON encodes Amount as a JSON number, as expected by Akahu.
+func (r TransferRequest) MarshalJSON() ([]byte, error) {
+	type alias TransferRequest
+	return json.Marshal(struct {
+		alias
+		Amount json.Number `json:"amount"`
+	}{alias(r), json.Number(r.Amount.String())})
+}
+
+// Create initiates a transfer between two of the user's connected accounts, returning the ID of the transfer.
+// An Idempotency-Key header is always sent, see IdempotencyOptions for how it is chosen.
+//
+// Akahu docs: https://developers.akahu.nz/reference/post_transfers
+func (s *TransfersService) Create(ctx context.Context, userAccessToken string, body TransferRequest, opts IdempotencyOptions) (*string, *APIResponse, error) {
+	if err := checkID("from", body.From); err != nil {
+		return nil, nil, err
+	}
+	if err := checkID("to", body.To); err != nil {
+		return nil, nil, err
+	}
+
+	return s.client.createIdempotent(ctx, "akahu.Transfers.Create", transfersPath, userAccessToken, body, opts)
+}
@@ -2,15 +2,21 @@ package akahu
 
 import (
 	"net/url"
+	"strings"
 	"time"
 )
 
-func paramsWithDateRange(startTime, endTime time.Time) url.Values {
+// paramsWithDateRange returns the start and end query parameters, or a *ValidationError if startTime isn't before endTime.
+func paramsWithDateRange(startTime, endTime time.Time) (url.Values, error) {
+	if err := checkDateRange(startTime, endTime); err != nil {
+		return nil, err
+	}
+
 	queryParams := url.Values{}
 	queryParams.Add("start", startTime.Format(time.RFC3339))
 	queryParams.Add("end", endTime.Format(time.RFC3339))
 
-	return queryParams
+	return queryParams, nil
 }
 
 func pathWithParams(path string, values url.Values) string {
@@ -19,3 +25,19 @@ func pathWithParams(path string, values url.Values) string {
 
 	return encodedPath.String()
 }
+
+// joinPath joins the segments of a URL path, escaping each segment so that an ID containing characters such as '/',
+// '?' or ".." can't change the endpoint that is called.
+func joinPath(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		if segment == "." || segment == ".." {
+			// PathEscape leaves dots alone, but dot segments would be resolved against the base URL.
+			escaped[i] = strings.ReplaceAll(segment, ".", "%2E")
+			continue
+		}
+		escaped[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(escaped, "/")
+}
@@ -0,0 +1,192 @@
+package akahu
+
+import (
+	"context"
+	"time"
+)
+
+// UserClient is a view of the Client for a single user, which calls the user scoped endpoints with the user's
+// access token so it doesn't have to be passed to every call.
+type UserClient struct {
+	client          *Client
+	userID          string
+	userAccessToken string
+
+	Accounts     *UserAccountsService
+	Auth         *UserAuthService
+	Me           *UserMeService
+	Payments     *UserPaymentsService
+	Transactions *UserTransactionsService
+	Transfers    *UserTransfersService
+	Webhooks     *UserWebhooksService
+}
+
+type userService struct {
+	user *UserClient
+}
+
+type UserAccountsService userService
+type UserAuthService userService
+type UserMeService userService
+type UserPaymentsService userService
+type UserTransactionsService userService
+type UserTransfersService userService
+type UserWebhooksService userService
+
+// ForUser creates a UserClient that makes calls with the given user access token.
+func (c *Client) ForUser(userAccessToken string) *UserClient {
+	return c.newUserClient("", userAccessToken)
+}
+
+// ForUserID creates a UserClient for a user whose access token is held in Client.Tokens.
+// ErrTokenNotFound is returned if there is no token stored for the user.
+func (c *Client) ForUserID(ctx context.Context, userID string) (*UserClient, error) {
+	token, err := c.Tokens.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.newUserClient(userID, token), nil
+}
+
+func (c *Client) newUserClient(userID, userAccessToken string) *UserClient {
+	u := &UserClient{
+		client:          c,
+		userID:          userID,
+		userAccessToken: userAccessToken,
+	}
+	u.Accounts = &UserAccountsService{user: u}
+	u.Auth = &UserAuthService{user: u}
+	u.Me = &UserMeService{user: u}
+	u.Payments = &UserPaymentsService{user: u}
+	u.Transactions = &UserTransactionsService{user: u}
+	u.Transfers = &UserTransfersService{user: u}
+	u.Webhooks = &UserWebhooksService{user: u}
+
+	return u
+}
+
+// UserID returns the ID of the user, or an empty string if the UserClient was created with ForUser.
+func (u *UserClient) UserID() string {
+	return u.userID
+}
+
+// List calls AccountsService.List for the user.
+func (s *UserAccountsService) List(ctx context.Context) ([]AccountResponse, *APIResponse, error) {
+	return s.user.client.Accounts.List(ctx, s.user.userAccessToken)
+}
+
+// Get calls AccountsService.Get for the user.
+func (s *UserAccountsService) Get(ctx context.Context, ID AccountID) (*AccountResponse, *APIResponse, error) {
+	return s.user.client.Accounts.Get(ctx, s.user.userAccessToken, ID)
+}
+
+// Revoke calls AccountsService.Revoke for the user.
+func (s *UserAccountsService) Revoke(ctx context.Context, ID AccountID) (bool, *APIResponse, error) {
+	return s.user.client.Accounts.Revoke(ctx, s.user.userAccessToken, ID)
+}
+
+// RevokeToken calls AuthService.RevokeToken for the user, which also removes the token from Client.Tokens.
+func (s *UserAuthService) RevokeToken(ctx context.Context) (bool, *APIResponse, error) {
+	return s.user.client.Auth.RevokeToken(ctx, s.user.userAccessToken)
+}
+
+// Introspect calls AuthService.Introspect for the user.
+func (s *UserAuthService) Introspect(ctx context.Context) (*TokenIntrospection, *APIResponse, error) {
+	return s.user.client.Auth.Introspect(ctx, s.user.userAccessToken)
+}
+
+// Get calls MeService.Get for the user.
+func (s *UserMeService) Get(ctx context.Context) (*MeResponse, *APIResponse, error) {
+	return s.user.client.Me.Get(ctx, s.user.userAccessToken)
+}
+
+// Create calls PaymentsService.Create for the user.
+func (s *UserPaymentsService) Create(ctx context.Context, body PaymentRequest, opts IdempotencyOptions) (*string, *APIResponse, error) {
+	return s.user.client.Payments.Create(ctx, s.user.userAccessToken, body, opts)
+}
+
+// List calls TransactionsService.List for the user.
+func (s *UserTransactionsService) List(ctx context.Context, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
+	return s.user.client.Transactions.List(ctx, s.user.userAccessToken, startTime, endTime)
+}
+
+// ListPending calls TransactionsService.ListPending for the user.
+func (s *UserTransactionsService) ListPending(ctx context.Context, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
+	return s.user.client.Transactions.ListPending(ctx, s.user.userAccessToken, startTime, endTime)
+}
+
+// ListByAccount calls TransactionsService.ListByAccount for the user.
+func (s *UserTransactionsService) ListByAccount(ctx context.Context, accountID AccountID, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
+	return s.user.client.Transactions.ListByAccount(ctx, s.user.userAccessToken, accountID, startTime, endTime)
+}
+
+// ListPendingByAccount calls TransactionsService.ListPendingByAccount for the user.
+func (s *UserTransactionsService) ListPendingByAccount(ctx context.Context, accountID AccountID, startTime, endTime time.Time) ([]TransactionResponse, *APIResponse, error) {
+	return s.user.client.Transactions.ListPendingByAccount(ctx, s.user.userAccessToken, accountID, startTime, endTime)
+}
+
+// Get calls TransactionsService.Get for the user.
+func (s *UserTransactionsService) Get(ctx context.Context, id TransactionID) (*TransactionResponse, *APIResponse, error) {
+	return s.user.client.Transactions.Get(ctx, s.user.userAccessToken, id)
+}
+
+// GetByIds calls TransactionsService.GetByIds for the user.
+func (s *UserTransactionsService) GetByIds(ctx context.Context, ids ...TransactionID) ([]TransactionResponse, *APIResponse, error) {
+	return s.user.client.Transactions.GetByIds(ctx, s.user.userAccessToken, ids...)
+}
+
+// LookupByIds calls TransactionsService.LookupByIds for the user.
+func (s *UserTransactionsService) LookupByIds(ctx context.Context, ids ...TransactionID) (*TransactionLookup, *APIResponse, error) {
+	return s.user.client.Transactions.LookupByIds(ctx, s.user.userAccessToken, ids...)
+}
+
+// Create calls TransfersService.Create for the user.
+func (s *UserTransfersService) Create(ctx context.Context, body TransferRequest, opts IdempotencyOptions) (*string, *APIResponse, error) {
+	return s.user.client.Transfers.Create(ctx, s.user.userAccessToken, body, opts)
+}
+
+// List calls WebhooksService.List for the user.
+func (s *UserWebhooksService) List(ctx context.Context) ([]WebhookResponse, *APIResponse, error) {
+	return s.user.client.Webhooks.List(ctx, s.user.userAccessToken)
+}
+
+// ListEvents calls WebhooksService.ListEvents for the user.
+func (s *UserWebhooksService) ListEvents(ctx context.Context, status string, startTime, endTime time.Time) ([]WebHookEventResponse, *APIResponse, error) {
+	return s.user.client.Webhooks.ListEvents(ctx, s.user.userAccessToken, status, startTime, endTime)
+}
+
+// ListEventsPage calls WebhooksService.ListEventsPage for the user.
+func (s *UserWebhooksService) ListEventsPage(ctx context.Context, status string, startTime, endTime time.Time, cursor string) ([]WebHookEventResponse, *APIResponse, error) {
+	return s.user.client.Webhooks.ListEventsPage(ctx, s.user.userAccessToken, status, startTime, endTime, cursor)
+}
+
+// Subscribe calls WebhooksService.Subscribe for the user.
+// If the UserClient was created with ForUserID and body has no State, the user ID is used as the state,
+// which allows Client.EvictRevokedToken to match TOKEN webhooks to the stored token.
+func (s *UserWebhooksService) Subscribe(ctx context.Context, body WebhookSubscribeRequest) (*WebhookID, *APIResponse, error) {
+	if body.State == "" {
+		body.State = s.user.userID
+	}
+
+	return s.user.client.Webhooks.Subscribe(ctx, s.user.userAccessToken, body)
+}
+
+// EnsureSubscriptions calls WebhooksService.EnsureSubscriptions for the user.
+// As with Subscribe, desired webhooks with no State use the user ID as their state.
+func (s *UserWebhooksService) EnsureSubscriptions(ctx context.Context, desired []WebhookSubscribeRequest) (*SubscriptionChanges, *APIResponse, error) {
+	withState := make([]WebhookSubscribeRequest, len(desired))
+	for i, d := range desired {
+		if d.State == "" {
+			d.State = s.user.userID
+		}
+		withState[i] = d
+	}
+
+	return s.user.client.Webhooks.EnsureSubscriptions(ctx, s.user.userAccessToken, withState)
+}
+
+// Unsubscribe calls WebhooksService.Unsubscribe for the user.
+func (s *UserWebhooksService) Unsubscribe(ctx context.Context, id WebhookID) (bool, *APIResponse, error) {
+	return s.user.client.Webhooks.Unsubscribe(ctx, s.user.userAccessToken, id)
+}
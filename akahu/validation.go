@@ -0,0 +1,64 @@
+package akahu
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ValidationError is returned before a request is sent when an argument is invalid, such as an empty or malformed ID,
+// or a date range that doesn't end after it starts.
+type ValidationError struct {
+	// Field is the name of the invalid argument, e.g. "accountID".
+	Field string
+	// Reason describes what is wrong with it.
+	Reason string
+	// Err is the underlying error, e.g. an *InvalidIDError, if any.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("akahu: invalid %s: %s", e.Field, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// checkID returns a *ValidationError for field if id is empty or doesn't have the prefix for its type.
+func checkID[T validatedID](field string, id T) error {
+	err := id.Validate()
+	if err == nil {
+		return nil
+	}
+
+	reason := err.Error()
+	var invalid *InvalidIDError
+	if errors.As(err, &invalid) {
+		reason = fmt.Sprintf("%q is not a %s, expected %q followed by letters and digits", invalid.ID, invalid.Type, invalid.Prefix)
+	}
+	if id == "" {
+		reason = "must not be empty"
+	}
+
+	return &ValidationError{Field: field, Reason: reason, Err: err}
+}
+
+func checkNotEmpty(field, value string) error {
+	if value == "" {
+		return &ValidationError{Field: field, Reason: "must not be empty"}
+	}
+
+	return nil
+}
+
+func checkDateRange(startTime, endTime time.Time) error {
+	if !startTime.Before(endTime) {
+		return &ValidationError{
+			Field:  "endTime",
+			Reason: fmt.Sprintf("%s is not after startTime %s", endTime.Format(time.RFC3339), startTime.Format(time.RFC3339)),
+		}
+	}
+
+	return nil
+}
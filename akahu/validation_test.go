@@ -0,0 +1,167 @@
+package akahu
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidation(t *testing.T) {
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("expected no request to be sent, actual %s %s", req.Method, req.URL)
+		return nil, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+	ctx := context.TODO()
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, -1, 0)
+
+	tests := []struct {
+		name          string
+		call          func() error
+		expectedField string
+	}{
+		{
+			name: "with empty account ID",
+			call: func() error {
+				_, _, err := client.Accounts.Get(ctx, "user_token_1", "")
+				return err
+			},
+			expectedField: "accountID",
+		},
+		{
+			name: "with transaction ID as account ID",
+			call: func() error {
+				_, _, err := client.Accounts.Revoke(ctx, "user_token_1", "trans_1111111111111111111111111")
+				return err
+			},
+			expectedField: "accountID",
+		},
+		{
+			name: "with path in account ID",
+			call: func() error {
+				_, _, err := client.Transactions.ListByAccount(ctx, "user_token_1", "acc_1/../../me", start, end)
+				return err
+			},
+			expectedField: "accountID",
+		},
+		{
+			name: "with malformed connection ID",
+			call: func() error {
+				_, _, err := client.Connections.Get(ctx, "../accounts")
+				return err
+			},
+			expectedField: "connectionID",
+		},
+		{
+			name: "with empty transaction ID",
+			call: func() error {
+				_, _, err := client.Transactions.Get(ctx, "user_token_1", "")
+				return err
+			},
+			expectedField: "transactionID",
+		},
+		{
+			name: "with malformed transaction ID in batch",
+			call: func() error {
+				_, _, err := client.Transactions.GetByIds(ctx, "user_token_1", "trans_1", "acc_1")
+				return err
+			},
+			expectedField: "ids[1]",
+		},
+		{
+			name: "with account ID and reversed dates",
+			call: func() error {
+				_, _, err := client.Transactions.ListByAccount(ctx, "user_token_1", "acc_1", end, start)
+				return err
+			},
+			expectedField: "endTime",
+		},
+		{
+			name: "with equal dates",
+			call: func() error {
+				_, _, err := client.Transactions.List(ctx, "user_token_1", start, start)
+				return err
+			},
+			expectedField: "endTime",
+		},
+		{
+			name: "with empty webhook ID",
+			call: func() error {
+				_, _, err := client.Webhooks.Unsubscribe(ctx, "user_token_1", "")
+				return err
+			},
+			expectedField: "webhookID",
+		},
+		{
+			name: "with empty public key ID",
+			call: func() error {
+				_, _, err := client.Webhooks.GetPublicKey(ctx, "")
+				return err
+			},
+			expectedField: "id",
+		},
+		{
+			name: "with empty event status",
+			call: func() error {
+				_, _, err := client.Webhooks.ListEvents(ctx, "user_token_1", "", start, end)
+				return err
+			},
+			expectedField: "status",
+		},
+		{
+			name: "with transfer to empty account",
+			call: func() error {
+				_, _, err := client.Transfers.Create(ctx, "user_token_1", TransferRequest{From: "acc_1"}, IdempotencyOptions{})
+				return err
+			},
+			expectedField: "to",
+		},
+		{
+			name: "with payment from malformed account",
+			call: func() error {
+				_, _, err := client.Payments.Create(ctx, "user_token_1", PaymentRequest{From: "1"}, IdempotencyOptions{})
+				return err
+			},
+			expectedField: "from",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call()
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected *ValidationError, actual %v", err)
+			}
+			if validationErr.Field != test.expectedField {
+				t.Fatalf("expected field %s, actual %s (%v)", test.expectedField, validationErr.Field, err)
+			}
+		})
+	}
+}
+
+func TestValidation_EscapesPath(t *testing.T) {
+	var requested string
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.EscapedPath()
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(errorResponseJsonWithMessage))}, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+	_, _, _ = client.Webhooks.GetPublicKey(context.TODO(), "1/../../me?x=1")
+	if expected := "/v1/keys/1%2F..%2F..%2Fme%3Fx=1"; requested != expected {
+		t.Fatalf("expected path %s, actual %s", expected, requested)
+	}
+
+	_, _, _ = client.Webhooks.GetPublicKey(context.TODO(), "..")
+	if expected := "/v1/keys/%2E%2E"; requested != expected {
+		t.Fatalf("expected path %s, actual %s", expected, requested)
+	}
+}
@@ -0,0 +1,349 @@
+package akahu
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupStore suppresses duplicate webhooks within a short window. It is used by WebhookHandler for live deliveries,
+// and by WebhookDispatcher.Recover for recovered events.
+//
+// It does not guarantee that a webhook is processed at most once. Akahu doesn't send the ID of a webhook event with
+// its live delivery, so webhooks are identified by their payload, and claims expire after the store's TTL:
+//   - A payload delivered again after the TTL, such as a late retry from Akahu, or a recovered event that was
+//     processed live but timed out, is processed again.
+//   - Distinct webhooks with identical payloads within the TTL, such as two balance updates of the same account, are
+//     treated as duplicates and only the first is processed.
+//
+// Handlers should still be idempotent. Choose a TTL that covers Akahu's retries of a delivery, but is shorter than
+// the time between webhooks you need to see, e.g. a few minutes.
+//
+// Not to be confused with IdempotencyKeyStore, which holds the keys of payments and transfers being made.
+type DedupStore interface {
+	// Claim records key until the store's TTL has passed, returning false if it is already claimed. Claims must be
+	// atomic, so that when receivers race to claim the same key only one of them succeeds.
+	Claim(ctx context.Context, key string) (bool, error)
+	// Release removes the claim on key, after the webhook failed to be processed, so that it can be claimed again.
+	Release(ctx context.Context, key string) error
+}
+
+// webhookDedupKey identifies a webhook by the SHA-256 hash of its payload, re-encoded so that the same payload
+// gets the same key whether it was delivered live or fetched from the webhook events.
+func webhookDedupKey(payload WebHookEventPayload) (string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(body)
+	return "webhook:" + hex.EncodeToString(hash[:]), nil
+}
+
+// checkDedupTTL panics if ttl isn't positive, as a store whose claims never expire would drop every later webhook
+// with the same payload.
+func checkDedupTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		panic("akahu: non-positive TTL for webhook dedup store")
+	}
+}
+
+// MemoryDedupStore is a DedupStore that holds claims in memory. It only suppresses duplicates within a single
+// process, use a FileDedupStore or SQLDedupStore when running several replicas.
+type MemoryDedupStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	claims    map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryDedupStore creates an empty MemoryDedupStore whose claims expire after ttl. It panics if ttl isn't
+// positive.
+func NewMemoryDedupStore(ttl time.Duration) *MemoryDedupStore {
+	checkDedupTTL(ttl)
+
+	return &MemoryDedupStore{ttl: ttl, now: time.Now, claims: map[string]time.Time{}}
+}
+
+func (s *MemoryDedupStore) Claim(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= s.ttl {
+		// Drop expired claims, so that the store doesn't grow without bound.
+		for k, expiresAt := range s.claims {
+			if !now.Before(expiresAt) {
+				delete(s.claims, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expiresAt, ok := s.claims[key]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+
+	s.claims[key] = now.Add(s.ttl)
+	return true, nil
+}
+
+func (s *MemoryDedupStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claims, key)
+	return nil
+}
+
+// FileDedupStore is a DedupStore that claims a key by creating a file for it in a directory, holding
+// the time the claim expires. Files are created exclusively, so replicas that share the directory, such as on a
+// network volume, never both claim the same key.
+type FileDedupStore struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewFileDedupStore creates a FileDedupStore that keeps its files in dir, which is created if it doesn't
+// exist. Claims expire after ttl. It panics if ttl isn't positive.
+func NewFileDedupStore(dir string, ttl time.Duration) (*FileDedupStore, error) {
+	checkDedupTTL(ttl)
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileDedupStore{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+func (s *FileDedupStore) Claim(_ context.Context, key string) (bool, error) {
+	now := s.now()
+	s.sweep(now)
+
+	path := s.path(key)
+	for attempt := 0; attempt < 3; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, err = f.WriteString(now.Add(s.ttl).UTC().Format(time.RFC3339Nano))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			return true, err
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return false, err
+		}
+
+		if !s.expired(path, now) {
+			return false, nil
+		}
+
+		// Move the expired claim aside before claiming the key again, so that only one replica replaces it.
+		taken, err := s.takeExpired(path, now)
+		if err != nil || !taken {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// takeExpired renames an expired claim out of the way and removes it. If another replica replaced the claim in the
+// meantime, its claim is restored instead and false is returned.
+func (s *FileDedupStore) takeExpired(path string, now time.Time) (bool, error) {
+	aside := fmt.Sprintf("%s.%d.expired", path, now.UnixNano())
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Another replica took it first, try to claim the key again.
+			return true, nil
+		}
+		return false, err
+	}
+	defer os.Remove(aside)
+
+	if !s.expired(aside, now) {
+		if err := os.Link(aside, path); err != nil && !errors.Is(err, fs.ErrExist) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// expired reports whether the claim in path has expired. Claims that can't be read are treated as expired, as they
+// are left by a replica that crashed while claiming.
+func (s *FileDedupStore) expired(path string, now time.Time) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return !errors.Is(err, fs.ErrNotExist)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, string(data))
+	if err != nil {
+		return true
+	}
+
+	return !now.Before(expiresAt)
+}
+
+// sweep removes expired claims at most once per TTL, so that the directory doesn't grow without bound.
+func (s *FileDedupStore) sweep(now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < s.ttl {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.Contains(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		if s.expired(path, now) {
+			_, _ = s.takeExpired(path, now)
+		}
+	}
+}
+
+func (s *FileDedupStore) Release(_ context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path hashes the key, so that it is always a valid file name.
+func (s *FileDedupStore) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(hash[:]))
+}
+
+// SQLDialect is the SQL used by a SQLDedupStore to claim and release keys.
+type SQLDialect struct {
+	// Claim inserts a key and the time its claim expires, or updates the expiry of an existing key whose claim expired
+	// before the current time. Its arguments are the key, the expiry and the current time, and it must affect no rows
+	// rather than fail if the key is claimed.
+	Claim string
+	// Release deletes a key.
+	Release string
+	// Prune deletes the keys whose claim expired before the time given as its argument.
+	Prune string
+}
+
+// SQL dialects for common databases, which claim keys in a table named akahu_webhook_dedup. It can be created with:
+//
+//	CREATE TABLE akahu_webhook_dedup (
+//		dedup_key VARCHAR(255) PRIMARY KEY,
+//		expires_at TIMESTAMP NOT NULL
+//	);
+//
+// MySQLDialect relies on updates that don't change a row reporting no affected rows, so the connection must not set
+// the clientFoundRows option.
+var (
+	PostgresDialect = SQLDialect{
+		Claim: "INSERT INTO akahu_webhook_dedup (dedup_key, expires_at) VALUES ($1, $2) " +
+			"ON CONFLICT (dedup_key) DO UPDATE SET expires_at = EXCLUDED.expires_at WHERE akahu_webhook_dedup.expires_at <= $3",
+		Release: "DELETE FROM akahu_webhook_dedup WHERE dedup_key = $1",
+		Prune:   "DELETE FROM akahu_webhook_dedup WHERE expires_at <= $1",
+	}
+	SQLiteDialect = SQLDialect{
+		Claim: "INSERT INTO akahu_webhook_dedup (dedup_key, expires_at) VALUES (?, ?) " +
+			"ON CONFLICT (dedup_key) DO UPDATE SET expires_at = excluded.expires_at WHERE akahu_webhook_dedup.expires_at <= ?",
+		Release: "DELETE FROM akahu_webhook_dedup WHERE dedup_key = ?",
+		Prune:   "DELETE FROM akahu_webhook_dedup WHERE expires_at <= ?",
+	}
+	MySQLDialect = SQLDialect{
+		Claim: "INSERT INTO akahu_webhook_dedup (dedup_key, expires_at) VALUES (?, ?) " +
+			"ON DUPLICATE KEY UPDATE expires_at = IF(expires_at <= ?, VALUES(expires_at), expires_at)",
+		Release: "DELETE FROM akahu_webhook_dedup WHERE dedup_key = ?",
+		Prune:   "DELETE FROM akahu_webhook_dedup WHERE expires_at <= ?",
+	}
+)
+
+// SQLDedupStore is a DedupStore backed by a database table with a unique key, which makes claims
+// atomic across every replica that shares the database.
+type SQLDedupStore struct {
+	db      *sql.DB
+	dialect SQLDialect
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewSQLDedupStore creates a SQLDedupStore that claims keys in db with the dialect's SQL. Claims expire
+// after ttl, and expired keys are pruned from the table as keys are claimed. It panics if ttl isn't positive.
+func NewSQLDedupStore(db *sql.DB, dialect SQLDialect, ttl time.Duration) *SQLDedupStore {
+	checkDedupTTL(ttl)
+
+	return &SQLDedupStore{db: db, dialect: dialect, ttl: ttl, now: time.Now}
+}
+
+func (s *SQLDedupStore) Claim(ctx context.Context, key string) (bool, error) {
+	now := s.now().UTC()
+	if err := s.prune(ctx, now); err != nil {
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx, s.dialect.Claim, key, now.Add(s.ttl), now)
+	if err != nil {
+		return false, fmt.Errorf("akahu: claiming webhook dedup key: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// prune deletes expired keys at most once per TTL.
+func (s *SQLDedupStore) prune(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < s.ttl {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, s.dialect.Prune, now); err != nil {
+		return fmt.Errorf("akahu: pruning webhook dedup keys: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLDedupStore) Release(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, s.dialect.Release, key); err != nil {
+		return fmt.Errorf("akahu: releasing webhook dedup key: %w", err)
+	}
+
+	return nil
+}
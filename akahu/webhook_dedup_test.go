@@ -0,0 +1,298 @@
+package akahu
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSQLDriver implements just enough of database/sql/driver to run the SQLDedupStore queries against a
+// map, with the unique key behaviour of a real table.
+type fakeSQLDriver struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+func (d *fakeSQLDriver) Open(string) (driver.Conn, error) { return &fakeSQLConn{d}, nil }
+
+type fakeSQLConn struct{ d *fakeSQLDriver }
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{c.d, query}, nil
+}
+func (c *fakeSQLConn) Close() error              { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeSQLStmt struct {
+	d     *fakeSQLDriver
+	query string
+}
+
+func (s *fakeSQLStmt) Close() error  { return nil }
+func (s *fakeSQLStmt) NumInput() int { return -1 }
+func (s *fakeSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		key, expiresAt, now := args[0].(string), args[1].(time.Time), args[2].(time.Time)
+		if existing, ok := s.d.keys[key]; ok && now.Before(existing) {
+			return driver.RowsAffected(0), nil
+		}
+		s.d.keys[key] = expiresAt
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "DELETE") && strings.Contains(s.query, "expires_at"):
+		now := args[0].(time.Time)
+		var deleted int64
+		for key, expiresAt := range s.d.keys {
+			if !now.Before(expiresAt) {
+				delete(s.d.keys, key)
+				deleted++
+			}
+		}
+		return driver.RowsAffected(deleted), nil
+	case strings.HasPrefix(s.query, "DELETE"):
+		delete(s.d.keys, args[0].(string))
+		return driver.RowsAffected(1), nil
+	}
+
+	return nil, fmt.Errorf("unexpected query %s", s.query)
+}
+
+var fakeSQLDrivers int64
+
+func openFakeSQL(t *testing.T) (*sql.DB, *fakeSQLDriver) {
+	name := fmt.Sprintf("akahu-fake-%d", atomic.AddInt64(&fakeSQLDrivers, 1))
+	d := &fakeSQLDriver{keys: map[string]time.Time{}}
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("sql.Open returned err %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db, d
+}
+
+func TestDedupStores(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T, now func() time.Time) (DedupStore, func() int)
+	}{
+		{
+			name: "with memory store",
+			store: func(t *testing.T, now func() time.Time) (DedupStore, func() int) {
+				store := NewMemoryDedupStore(time.Minute)
+				store.now = now
+				return store, func() int { return len(store.claims) }
+			},
+		},
+		{
+			name: "with file store",
+			store: func(t *testing.T, now func() time.Time) (DedupStore, func() int) {
+				dir := t.TempDir()
+				store, err := NewFileDedupStore(dir, time.Minute)
+				if err != nil {
+					t.Fatalf("NewFileDedupStore returned err %v", err)
+				}
+				store.now = now
+				return store, func() int {
+					entries, _ := os.ReadDir(dir)
+					return len(entries)
+				}
+			},
+		},
+		{
+			name: "with sql store",
+			store: func(t *testing.T, now func() time.Time) (DedupStore, func() int) {
+				db, d := openFakeSQL(t)
+				store := NewSQLDedupStore(db, PostgresDialect, time.Minute)
+				store.now = now
+				return store, func() int {
+					d.mu.Lock()
+					defer d.mu.Unlock()
+					return len(d.keys)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.TODO()
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			store, size := test.store(t, func() time.Time { return now })
+
+			// Only one of many concurrent claims on the same key succeeds.
+			var claimed int64
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if ok, err := store.Claim(ctx, "delivery:1"); err == nil && ok {
+						atomic.AddInt64(&claimed, 1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if claimed != 1 {
+				t.Fatalf("expected exactly 1 successful claim, actual %d", claimed)
+			}
+
+			if err := store.Release(ctx, "delivery:1"); err != nil {
+				t.Fatalf("Release returned err %v", err)
+			}
+			if ok, _ := store.Claim(ctx, "delivery:1"); !ok {
+				t.Fatalf("expected released key to be claimable")
+			}
+			if err := store.Release(ctx, "delivery:unknown"); err != nil {
+				t.Fatalf("expected releasing an unclaimed key to succeed, actual %v", err)
+			}
+
+			// Claims expire after the TTL, and expired claims are removed.
+			now = now.Add(59 * time.Second)
+			if ok, _ := store.Claim(ctx, "delivery:1"); ok {
+				t.Fatalf("expected key to still be claimed within the TTL")
+			}
+			now = now.Add(time.Second)
+			if ok, _ := store.Claim(ctx, "delivery:1"); !ok {
+				t.Fatalf("expected key to be claimable once its claim expired")
+			}
+			if ok, _ := store.Claim(ctx, "delivery:2"); !ok {
+				t.Fatalf("expected unclaimed key to be claimable")
+			}
+			now = now.Add(2 * time.Minute)
+			if ok, _ := store.Claim(ctx, "delivery:3"); !ok {
+				t.Fatalf("expected unclaimed key to be claimable")
+			}
+			if actual := size(); actual != 1 {
+				t.Fatalf("expected expired claims to be removed leaving 1, actual %d", actual)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_Dedup(t *testing.T) {
+	publicKeyJson := fmt.Sprintf(itemResponseJson, fmt.Sprintf("%q", testWebhookPublicKey))
+	client := setupClient(t, publicKeyJson, http.MethodGet, http.StatusOK)
+
+	failures := 1
+	dispatched := 0
+	dispatcher := NewWebhookDispatcher()
+	dispatcher.HandleFunc(Account, func(ctx context.Context, payload WebHookEventPayload) error {
+		dispatched++
+		if failures > 0 {
+			failures--
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	// Two replicas sharing a store.
+	store := NewMemoryDedupStore(time.Minute)
+	replicas := []*WebhookHandler{NewWebhookHandler(client, dispatcher), NewWebhookHandler(client, dispatcher)}
+	for _, replica := range replicas {
+		replica.Dedup = store
+	}
+
+	deliver := func(handler *WebhookHandler) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(testWebhookBody))
+		req.Header.Set("X-Akahu-Signature", testWebhookSignature)
+		req.Header.Set("X-Akahu-Signing-Key", "1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	// The first delivery fails and is released, so Akahu's retry is processed, and later duplicates are not.
+	expectedStatuses := []int{http.StatusInternalServerError, http.StatusOK, http.StatusOK, http.StatusOK}
+	for i, expected := range expectedStatuses {
+		if actual := deliver(replicas[i%2]); actual != expected {
+			t.Fatalf("expected delivery %d status %d, actual %d", i, expected, actual)
+		}
+	}
+
+	if dispatched != 2 {
+		t.Fatalf("expected the webhook to be dispatched twice, actual %d", dispatched)
+	}
+}
+
+func TestWebhookHandler_Dedup_Expires(t *testing.T) {
+	publicKeyJson := fmt.Sprintf(itemResponseJson, fmt.Sprintf("%q", testWebhookPublicKey))
+	client := setupClient(t, publicKeyJson, http.MethodGet, http.StatusOK)
+
+	dispatched := 0
+	dispatcher := NewWebhookDispatcher()
+	dispatcher.HandleFunc(Account, func(ctx context.Context, payload WebHookEventPayload) error {
+		dispatched++
+		return nil
+	})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryDedupStore(time.Minute)
+	store.now = func() time.Time { return now }
+	handler := NewWebhookHandler(client, dispatcher)
+	handler.Dedup = store
+
+	deliver := func() {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(testWebhookBody))
+		req.Header.Set("X-Akahu-Signature", testWebhookSignature)
+		req.Header.Set("X-Akahu-Signing-Key", "1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, actual %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	// A redelivery is skipped, but the same balance update an hour later is a new webhook.
+	deliver()
+	now = now.Add(10 * time.Second)
+	deliver()
+	now = now.Add(time.Hour)
+	deliver()
+
+	if dispatched != 2 {
+		t.Fatalf("expected identical webhooks at different times to both be dispatched, actual %d", dispatched)
+	}
+}
+
+func TestDedupStores_NonPositiveTTL(t *testing.T) {
+	db, _ := openFakeSQL(t)
+	constructors := map[string]func(){
+		"memory": func() { NewMemoryDedupStore(0) },
+		"file":   func() { _, _ = NewFileDedupStore(t.TempDir(), 0) },
+		"sql":    func() { NewSQLDedupStore(db, PostgresDialect, -time.Minute) },
+	}
+
+	for name, constructor := range constructors {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected the %s store to panic without a TTL", name)
+				}
+			}()
+
+			constructor()
+		}()
+	}
+}
@@ -0,0 +1,371 @@
+package akahu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	webhookSignatureHeader  = "X-Akahu-Signature"
+	webhookSigningKeyHeader = "X-Akahu-Signing-Key"
+	maxWebhookBodySize      = 1 << 20
+	// minKeyLookupInterval is how long a signing key that couldn't be fetched isn't fetched again, so that requests
+	// repeating a made-up key ID don't make the handler call Akahu for each of them.
+	minKeyLookupInterval = 10 * time.Second
+)
+
+// WebhookHandlerFunc handles a verified webhook payload. Returning an error tells Akahu that the webhook wasn't
+// processed, so that it is retried.
+type WebhookHandlerFunc func(ctx context.Context, payload WebHookEventPayload) error
+
+// WebhookDispatcher routes webhook payloads to a handler for each type of webhook. The same handlers are used for live
+// deliveries through a WebhookHandler, and for events recovered with Recover.
+type WebhookDispatcher struct {
+	mu       sync.RWMutex
+	handlers map[WebhookType]WebhookHandlerFunc
+	tokens   *Client
+}
+
+// NewWebhookDispatcher creates a WebhookDispatcher with no handlers.
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{handlers: map[WebhookType]WebhookHandlerFunc{}}
+}
+
+// HandleFunc sets the handler for a type of webhook, replacing any existing handler.
+func (d *WebhookDispatcher) HandleFunc(webhookType WebhookType, handler WebhookHandlerFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.handlers[webhookType] = handler
+}
+
+// EvictRevokedTokens removes revoked tokens from client.Tokens with Client.EvictRevokedToken before dispatching each
+// TOKEN webhook, so that a handler for TOKEN webhooks isn't needed to keep the store up to date. NewWebhookHandler
+// calls it with the handler's client.
+func (d *WebhookDispatcher) EvictRevokedTokens(client *Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.tokens = client
+}
+
+// Dispatch calls the handler for the payload's type. Payloads without a handler are ignored.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, payload WebHookEventPayload) error {
+	d.mu.RLock()
+	handler, ok := d.handlers[payload.WebhookType]
+	tokens := d.tokens
+	d.mu.RUnlock()
+
+	if tokens != nil && tokens.Tokens != nil {
+		if err := tokens.EvictRevokedToken(ctx, payload); err != nil {
+			return err
+		}
+	}
+
+	if !ok {
+		return nil
+	}
+
+	return handler(ctx, payload)
+}
+
+// WebhookHandler is an http.Handler that receives webhooks from Akahu. It verifies the signature of each request
+// with the signing key it names, which is fetched with WebhooksService.GetPublicKey and cached, then dispatches the
+// payload. Each key is fetched once however many requests need it at the same time. A key that couldn't be fetched
+// isn't fetched again for 10 seconds: requests naming it in that time get a 401 response if Akahu doesn't know the
+// key, or a 500 so that Akahu retries them if the lookup failed for another reason.
+//
+// Requests with a missing or invalid signature get a 401 response. If the handler returns an error the response is
+// a 500, so that Akahu retries the webhook. Handlers that may not finish within Akahu's delivery timeout should be
+// run through a WebhookQueue instead.
+type WebhookHandler struct {
+	client     *Client
+	dispatcher *WebhookDispatcher
+
+	// Dedup, if set, skips webhooks whose payload was already claimed within the store's TTL, such as when Akahu
+	// delivers one again, including across replicas of the receiver that share the store. It is not a guarantee that
+	// each webhook is processed once, see DedupStore.
+	Dedup DedupStore
+	// Queue, if set, receives verified webhooks instead of the dispatcher, so that Akahu gets a response as soon as
+	// the webhook is stored. The queue's workers then dispatch it, retrying failures.
+	Queue *WebhookQueue
+
+	now func() time.Time
+
+	mu            sync.Mutex
+	keys          map[string]string
+	keyLookups    map[string]*keyLookup
+	failedLookups map[string]failedKeyLookup
+	lastSweep     time.Time
+}
+
+// keyLookup is a fetch of a signing key in progress, which other requests for the same key wait for.
+type keyLookup struct {
+	done chan struct{}
+	key  string
+	err  error
+}
+
+type failedKeyLookup struct {
+	at  time.Time
+	err error
+}
+
+// NewWebhookHandler creates a WebhookHandler that dispatches verified webhooks to dispatcher, which evicts revoked
+// tokens from client.Tokens, see WebhookDispatcher.EvictRevokedTokens.
+func NewWebhookHandler(client *Client, dispatcher *WebhookDispatcher) *WebhookHandler {
+	dispatcher.EvictRevokedTokens(client)
+
+	return &WebhookHandler{
+		client:        client,
+		dispatcher:    dispatcher,
+		now:           time.Now,
+		keys:          map[string]string{},
+		keyLookups:    map[string]*keyLookup{},
+		failedLookups: map[string]failedKeyLookup{},
+	}
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "Unable to read body.", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := h.verify(r.Context(), r.Header, body)
+	if errors.Is(err, errInvalidWebhookSignature) {
+		http.Error(w, "Invalid signature.", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unable to verify webhook.", http.StatusInternalServerError)
+		return
+	}
+
+	var key string
+	if h.Dedup != nil {
+		key, err = webhookDedupKey(*payload)
+		if err != nil {
+			http.Error(w, "Unable to process webhook.", http.StatusInternalServerError)
+			return
+		}
+		claimed, err := h.Dedup.Claim(r.Context(), key)
+		if err != nil {
+			http.Error(w, "Unable to process webhook.", http.StatusInternalServerError)
+			return
+		}
+		if !claimed {
+			// Already processed, or being processed by another replica.
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	if h.Queue != nil {
+		if err := h.Queue.Enqueue(r.Context(), *payload); err != nil {
+			if h.Dedup != nil {
+				_ = h.Dedup.Release(r.Context(), key)
+			}
+			http.Error(w, "Unable to queue webhook.", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := h.dispatcher.Dispatch(r.Context(), *payload); err != nil {
+		if h.Dedup != nil {
+			// Let Akahu's retry of this delivery be processed.
+			_ = h.Dedup.Release(r.Context(), key)
+		}
+		http.Error(w, "Unable to process webhook.", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+var (
+	errInvalidWebhookSignature = errors.New("akahu: invalid webhook signature")
+	errKeyLookupLimited        = errors.New("akahu: too many webhook signing key lookups")
+)
+
+func (h *WebhookHandler) verify(ctx context.Context, header http.Header, body []byte) (*WebHookEventPayload, error) {
+	signature, keyID := header.Get(webhookSignatureHeader), header.Get(webhookSigningKeyHeader)
+	if signature == "" || keyID == "" {
+		return nil, errInvalidWebhookSignature
+	}
+
+	publicKey, err := h.publicKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := ValidateWebhookSignature(publicKey, signature, body)
+	if err != nil || !valid {
+		return nil, errInvalidWebhookSignature
+	}
+
+	var payload WebHookEventPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
+
+// publicKey returns the signing key with the given ID, fetching it if it isn't cached. The lock is only held to
+// check and update the cache, so requests for cached keys aren't held up while another key is fetched.
+func (h *WebhookHandler) publicKey(ctx context.Context, keyID string) (string, error) {
+	h.mu.Lock()
+	if key, ok := h.keys[keyID]; ok {
+		h.mu.Unlock()
+		return key, nil
+	}
+
+	if lookup, ok := h.keyLookups[keyID]; ok {
+		h.mu.Unlock()
+
+		select {
+		case <-lookup.done:
+			return lookup.key, lookup.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	now := h.now()
+	if failed, ok := h.failedLookups[keyID]; ok && now.Sub(failed.at) < minKeyLookupInterval {
+		h.mu.Unlock()
+
+		if errors.Is(failed.err, errInvalidWebhookSignature) {
+			return "", errInvalidWebhookSignature
+		}
+		return "", errKeyLookupLimited
+	}
+
+	lookup := &keyLookup{done: make(chan struct{})}
+	h.keyLookups[keyID] = lookup
+	h.mu.Unlock()
+
+	lookup.key, lookup.err = h.fetchPublicKey(ctx, keyID)
+
+	h.mu.Lock()
+	delete(h.keyLookups, keyID)
+	switch {
+	case lookup.err == nil:
+		h.keys[keyID] = lookup.key
+	case ctx.Err() == nil:
+		// Lookups cut short by the request going away aren't the key's fault, so don't hold them against it.
+		h.sweepFailedLookups(now)
+		h.failedLookups[keyID] = failedKeyLookup{at: now, err: lookup.err}
+	}
+	h.mu.Unlock()
+	close(lookup.done)
+
+	return lookup.key, lookup.err
+}
+
+func (h *WebhookHandler) fetchPublicKey(ctx context.Context, keyID string) (string, error) {
+	key, res, err := h.client.Webhooks.GetPublicKey(ctx, keyID)
+	if err != nil {
+		return "", err
+	}
+	if !res.Success || key == nil {
+		// An unknown key ID is most likely a forged request.
+		return "", errInvalidWebhookSignature
+	}
+
+	return *key, nil
+}
+
+// sweepFailedLookups forgets failed lookups that are no longer limited, at most once per interval, so that made-up
+// key IDs don't build up. h.mu must be held.
+func (h *WebhookHandler) sweepFailedLookups(now time.Time) {
+	if now.Sub(h.lastSweep) < minKeyLookupInterval {
+		return
+	}
+	h.lastSweep = now
+
+	for keyID, failed := range h.failedLookups {
+		if now.Sub(failed.at) >= minKeyLookupInterval {
+			delete(h.failedLookups, keyID)
+		}
+	}
+}
+
+// RecoveryResult is the outcome of WebhookDispatcher.Recover.
+type RecoveryResult struct {
+	// Dispatched are the IDs of the events that were dispatched successfully.
+	Dispatched []string
+	// Skipped are the IDs of the events whose payload was claimed within the store's TTL, usually because they were
+	// already processed.
+	Skipped []string
+	// Failed are the errors returned by the handlers, keyed by event ID. These events are dispatched again by the next recovery.
+	Failed map[string]error
+}
+
+// Recover re-dispatches webhook events that weren't delivered, such as while your webhook receiver was down.
+// It pages through the events with the given status (usually FAILED or RETRY) within the 'start' and 'end' time range.
+// Each event is claimed in claims by its payload before it is dispatched, the same way WebhookHandler claims live
+// deliveries, so events that were already processed live or recovered within the store's TTL are skipped, even by a
+// recovery running in parallel. The claim is released if the handler fails.
+//
+// If Akahu returns an unsuccessful response, recovery stops and the response is returned with the result so far.
+func (d *WebhookDispatcher) Recover(ctx context.Context, client *Client, userAccessToken, status string, startTime, endTime time.Time, claims DedupStore) (*RecoveryResult, *APIResponse, error) {
+	result := &RecoveryResult{Failed: map[string]error{}}
+
+	var cursor string
+	for {
+		events, res, err := client.Webhooks.ListEventsPage(ctx, userAccessToken, status, startTime, endTime, cursor)
+		if err != nil {
+			return result, nil, err
+		}
+		if !res.Success {
+			return result, res, nil
+		}
+
+		for _, event := range events {
+			key, err := webhookDedupKey(event.Payload)
+			if err != nil {
+				return result, res, err
+			}
+			claimed, err := claims.Claim(ctx, key)
+			if err != nil {
+				return result, res, err
+			}
+			if !claimed {
+				result.Skipped = append(result.Skipped, event.Id)
+				continue
+			}
+
+			if err := d.Dispatch(ctx, event.Payload); err != nil {
+				result.Failed[event.Id] = err
+				if err := claims.Release(ctx, key); err != nil {
+					return result, res, fmt.Errorf("akahu: event %s failed and its claim could not be released: %w", event.Id, err)
+				}
+				continue
+			}
+			result.Dispatched = append(result.Dispatched, event.Id)
+		}
+
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			return result, res, nil
+		}
+		cursor = res.NextCursor
+	}
+}
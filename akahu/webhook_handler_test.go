@@ -0,0 +1,422 @@
+package akahu
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	publicKeyJson, _ := json.Marshal(testWebhookPublicKey)
+
+	tests := []struct {
+		name           string
+		method         string
+		signature      string
+		signingKey     string
+		handlerErr     error
+		expectedStatus int
+		expectedCalls  int
+	}{
+		{
+			name:           "with valid signature",
+			method:         http.MethodPost,
+			signature:      testWebhookSignature,
+			signingKey:     "1",
+			expectedStatus: http.StatusOK,
+			expectedCalls:  1,
+		},
+		{
+			name:           "with invalid signature",
+			method:         http.MethodPost,
+			signature:      strings.Replace(testWebhookSignature, "FF", "AA", 1),
+			signingKey:     "1",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "with missing signing key",
+			method:         http.MethodPost,
+			signature:      testWebhookSignature,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "with handler error",
+			method:         http.MethodPost,
+			signature:      testWebhookSignature,
+			signingKey:     "1",
+			handlerErr:     errors.New("database unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  1,
+		},
+		{
+			name:           "with GET request",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyRequests := 0
+			client := setupClient(t, fmt.Sprintf(itemResponseJson, publicKeyJson), http.MethodGet, http.StatusOK, func(r *http.Request) {
+				keyRequests++
+				if r.URL.Path != "/v1/keys/1" {
+					t.Fatalf("expected public key request for key 1, actual %s", r.URL.Path)
+				}
+			})
+
+			var payloads []WebHookEventPayload
+			dispatcher := NewWebhookDispatcher()
+			dispatcher.HandleFunc(Account, func(ctx context.Context, payload WebHookEventPayload) error {
+				payloads = append(payloads, payload)
+				return test.handlerErr
+			})
+			handler := NewWebhookHandler(client, dispatcher)
+
+			// Deliver twice, to check that the public key is cached.
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(test.method, "/webhooks", strings.NewReader(testWebhookBody))
+				req.Header.Set("X-Akahu-Signature", test.signature)
+				req.Header.Set("X-Akahu-Signing-Key", test.signingKey)
+				rec := httptest.NewRecorder()
+
+				handler.ServeHTTP(rec, req)
+
+				if rec.Code != test.expectedStatus {
+					t.Fatalf("expected status %d, actual %d", test.expectedStatus, rec.Code)
+				}
+			}
+
+			if len(payloads) != 2*test.expectedCalls {
+				t.Fatalf("expected %d dispatched payloads, actual %d", 2*test.expectedCalls, len(payloads))
+			}
+			if test.expectedCalls > 0 {
+				if keyRequests != 1 {
+					t.Fatalf("expected public key to be fetched once, actual %d", keyRequests)
+				}
+				if payloads[0].ItemId != "acc_1111111111111111111111111" || payloads[0].WebhookCode != "UPDATE" {
+					t.Fatalf("unexpected payload %+v", payloads[0])
+				}
+			}
+		})
+	}
+}
+
+func deliverWebhook(handler http.Handler, keyID string) int {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(testWebhookBody))
+	req.Header.Set("X-Akahu-Signature", testWebhookSignature)
+	req.Header.Set("X-Akahu-Signing-Key", keyID)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestWebhookHandler_UnknownSigningKeys(t *testing.T) {
+	var keyRequests []string
+	client := setupClient(t, errorResponseJsonWithMessage, http.MethodGet, http.StatusNotFound, func(r *http.Request) {
+		keyRequests = append(keyRequests, r.URL.Path)
+	})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := NewWebhookHandler(client, NewWebhookDispatcher())
+	handler.now = func() time.Time { return now }
+
+	// A made-up key ID is only looked up once, however often it is repeated.
+	for i := 0; i < 10; i++ {
+		if actual := deliverWebhook(handler, "forged"); actual != http.StatusUnauthorized {
+			t.Fatalf("expected status %d, actual %d", http.StatusUnauthorized, actual)
+		}
+	}
+	if len(keyRequests) != 1 {
+		t.Fatalf("expected 1 key request, actual %v", keyRequests)
+	}
+
+	// It doesn't stop other keys being looked up.
+	deliverWebhook(handler, "2")
+	if !reflect.DeepEqual(keyRequests, []string{"/v1/keys/forged", "/v1/keys/2"}) {
+		t.Fatalf("expected key 2 to be looked up, actual %v", keyRequests)
+	}
+
+	now = now.Add(minKeyLookupInterval)
+	deliverWebhook(handler, "forged")
+	if len(keyRequests) != 3 {
+		t.Fatalf("expected another key request after the interval, actual %v", keyRequests)
+	}
+	if len(handler.failedLookups) != 1 {
+		t.Fatalf("expected expired failed lookups to be forgotten, actual %v", handler.failedLookups)
+	}
+}
+
+func TestWebhookHandler_ConcurrentKeyLookups(t *testing.T) {
+	publicKeyJson := fmt.Sprintf(itemResponseJson, fmt.Sprintf("%q", testWebhookPublicKey))
+	fetching, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var keyRequests []string
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		keyRequests = append(keyRequests, req.URL.Path)
+		mu.Unlock()
+
+		if req.URL.Path == "/v1/keys/1" {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(publicKeyJson))}, nil
+		}
+
+		close(fetching)
+		<-release
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(errorResponseJsonWithMessage))}, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+	handler := NewWebhookHandler(client, NewWebhookDispatcher())
+
+	if actual := deliverWebhook(handler, "1"); actual != http.StatusOK {
+		t.Fatalf("expected status %d, actual %d", http.StatusOK, actual)
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = deliverWebhook(handler, "2")
+		}()
+	}
+	<-fetching
+
+	// A cached key isn't held up by another key being fetched.
+	delivered := make(chan int)
+	go func() { delivered <- deliverWebhook(handler, "1") }()
+	select {
+	case actual := <-delivered:
+		if actual != http.StatusOK {
+			t.Fatalf("expected status %d, actual %d", http.StatusOK, actual)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected delivery with a cached key not to wait for another key to be fetched")
+	}
+
+	close(release)
+	wg.Wait()
+
+	if !reflect.DeepEqual(codes, []int{http.StatusUnauthorized, http.StatusUnauthorized}) {
+		t.Fatalf("expected both deliveries to be unauthorized, actual %v", codes)
+	}
+	if !reflect.DeepEqual(keyRequests, []string{"/v1/keys/1", "/v1/keys/2"}) {
+		t.Fatalf("expected each key to be fetched once, actual %v", keyRequests)
+	}
+}
+
+func TestWebhookHandler_EvictsRevokedTokens(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned err %v", err)
+	}
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)})
+	publicKeyJson, _ := json.Marshal(string(publicKey))
+
+	body := `{"webhook_type":"TOKEN","webhook_code":"DELETE","state":"user_1","item_id":"user_token_1"}`
+	hash := sha256.Sum256([]byte(body))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15 returned err %v", err)
+	}
+
+	client := setupClient(t, fmt.Sprintf(itemResponseJson, publicKeyJson), http.MethodGet, http.StatusOK)
+	_ = client.Tokens.Put(context.TODO(), "user_1", "user_token_1")
+	_ = client.Tokens.Put(context.TODO(), "user_2", "user_token_2")
+
+	// The token is already gone when the dispatcher's handler for TOKEN webhooks is called.
+	var stored []string
+	dispatcher := NewWebhookDispatcher()
+	dispatcher.HandleFunc(Token, func(ctx context.Context, payload WebHookEventPayload) error {
+		stored, _ = client.Tokens.UserIDs(ctx)
+		return nil
+	})
+	handler := NewWebhookHandler(client, dispatcher)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	req.Header.Set("X-Akahu-Signature", base64.StdEncoding.EncodeToString(signature))
+	req.Header.Set("X-Akahu-Signing-Key", "1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, actual %d", http.StatusOK, rec.Code)
+	}
+	if !reflect.DeepEqual(stored, []string{"user_2"}) {
+		t.Fatalf("expected only user_2's token to be stored, actual %v", stored)
+	}
+}
+
+// pagedEventsClient serves each page of events in turn, with a cursor to the next page.
+func pagedEventsClient(pages ...string) *Client {
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		cursor := req.URL.Query().Get("cursor")
+		page := 0
+		if cursor != "" {
+			_, _ = fmt.Sscanf(cursor, "page_%d", &page)
+		}
+
+		next := "null"
+		if page+1 < len(pages) {
+			next = fmt.Sprintf(`"page_%d"`, page+1)
+		}
+		body := fmt.Sprintf(`{ "success": true, "items": [%s], "cursor": { "next": %s } }`, pages[page], next)
+
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+
+	return NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+}
+
+func webhookEventJson(id, webhookType string) string {
+	return fmt.Sprintf(`{ "_id": "%s", "hook": "hook_1", "status": "FAILED", "payload": { "webhook_type": "%s", "webhook_code": "DEFAULT_UPDATE", "state": "user_1", "item_id": "%s" } }`, id, webhookType, id)
+}
+
+func TestWebhooksService_ListEvents_Pages(t *testing.T) {
+	client := pagedEventsClient(
+		webhookEventJson("event_1", "TRANSACTION")+","+webhookEventJson("event_2", "TRANSACTION"),
+		webhookEventJson("event_3", "ACCOUNT"),
+	)
+
+	events, res, err := client.Webhooks.ListEvents(context.TODO(), "user_token_1", string(Failed), time.Now().Add(-time.Hour), time.Now())
+	testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+	testClientResponse(t, []string{"event_1", "event_2", "event_3"}, ids, nil)
+}
+
+func TestWebhookDispatcher_Recover(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) DedupStore
+	}{
+		{
+			name: "with memory store",
+			store: func(t *testing.T) DedupStore {
+				return NewMemoryDedupStore(time.Minute)
+			},
+		},
+		{
+			name: "with file store",
+			store: func(t *testing.T) DedupStore {
+				store, _ := NewFileDedupStore(t.TempDir(), time.Minute)
+				return store
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.TODO()
+			client := pagedEventsClient(
+				webhookEventJson("event_1", "TRANSACTION")+","+webhookEventJson("event_2", "TRANSACTION"),
+				webhookEventJson("event_3", "TRANSACTION")+","+webhookEventJson("event_4", "ACCOUNT"),
+			)
+
+			claims := test.store(t)
+			key, _ := webhookDedupKey(WebHookEventPayload{WebhookType: Transaction, WebhookCode: "DEFAULT_UPDATE", State: "user_1", ItemId: "event_1"})
+			_, _ = claims.Claim(ctx, key)
+
+			failing := true
+			var dispatched []string
+			dispatcher := NewWebhookDispatcher()
+			dispatcher.HandleFunc(Transaction, func(ctx context.Context, payload WebHookEventPayload) error {
+				if payload.State != "user_1" {
+					t.Fatalf("expected payload state user_1, actual %s", payload.State)
+				}
+				dispatched = append(dispatched, payload.WebhookCode)
+				if failing && len(dispatched) == 2 {
+					return errors.New("handler failed")
+				}
+				return nil
+			})
+
+			start, end := time.Now().Add(-24*time.Hour), time.Now()
+			result, res, err := dispatcher.Recover(ctx, client, "user_token_1", string(Failed), start, end, claims)
+			testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+
+			// event_4 has no handler, so it is dispatched without doing anything.
+			if !reflect.DeepEqual(result.Dispatched, []string{"event_2", "event_4"}) {
+				t.Fatalf("expected dispatched event_2 and event_4, actual %v", result.Dispatched)
+			}
+			if !reflect.DeepEqual(result.Skipped, []string{"event_1"}) {
+				t.Fatalf("expected skipped event_1, actual %v", result.Skipped)
+			}
+			if _, ok := result.Failed["event_3"]; !ok || len(result.Failed) != 1 {
+				t.Fatalf("expected event_3 to fail, actual %v", result.Failed)
+			}
+
+			// Recovering the same window again only dispatches the event that failed.
+			failing = false
+			result, _, _ = dispatcher.Recover(ctx, client, "user_token_1", string(Failed), start, end, claims)
+			if !reflect.DeepEqual(result.Dispatched, []string{"event_3"}) {
+				t.Fatalf("expected dispatched event_3, actual %v", result.Dispatched)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatcher_Recover_AfterLiveDelivery(t *testing.T) {
+	publicKeyJson := fmt.Sprintf(itemResponseJson, fmt.Sprintf("%q", testWebhookPublicKey))
+	eventsJson := fmt.Sprintf(`{ "success": true, "items": [{ "_id": "event_1", "hook": "hook_1", "status": "FAILED", "payload": %s }], "cursor": { "next": null } }`, testWebhookBody)
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := eventsJson
+		if strings.HasPrefix(req.URL.Path, "/v1/keys/") {
+			body = publicKeyJson
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+	dispatched := 0
+	dispatcher := NewWebhookDispatcher()
+	dispatcher.HandleFunc(Account, func(ctx context.Context, payload WebHookEventPayload) error {
+		dispatched++
+		return nil
+	})
+
+	handler := NewWebhookHandler(client, dispatcher)
+	handler.Dedup = NewMemoryDedupStore(time.Minute)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(testWebhookBody))
+	req.Header.Set("X-Akahu-Signature", testWebhookSignature)
+	req.Header.Set("X-Akahu-Signing-Key", "1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, actual %d", http.StatusOK, rec.Code)
+	}
+
+	// Akahu didn't get the response, so the event is also recovered, but it was already processed.
+	result, res, err := dispatcher.Recover(context.TODO(), client, "user_token_1", string(Failed), time.Now().Add(-time.Hour), time.Now(), handler.Dedup)
+	testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+
+	if dispatched != 1 {
+		t.Fatalf("expected the webhook to be dispatched once, actual %d", dispatched)
+	}
+	if !reflect.DeepEqual(result.Skipped, []string{"event_1"}) {
+		t.Fatalf("expected skipped event_1, actual %v", result.Skipped)
+	}
+}
@@ -0,0 +1,639 @@
+package akahu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueueWorkers      = 4
+	defaultQueueMaxAttempts  = 5
+	defaultQueueBaseDelay    = time.Second
+	defaultQueueMaxDelay     = 5 * time.Minute
+	defaultQueuePollInterval = time.Second
+)
+
+// ErrQueuedWebhookNotFound is returned when replaying or discarding a dead letter that doesn't exist.
+var ErrQueuedWebhookNotFound = errors.New("akahu: queued webhook not found")
+
+// QueuedWebhook is a webhook payload waiting to be processed by a WebhookQueue, or that has been moved to its dead letters.
+type QueuedWebhook struct {
+	ID         string              `json:"id"`
+	Payload    WebHookEventPayload `json:"payload"`
+	EnqueuedAt time.Time           `json:"enqueued_at"`
+	// Attempts is how many times the webhook has failed to be processed.
+	Attempts int `json:"attempts"`
+	// NextAttemptAt is when the webhook will next be processed.
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// LastError is the error returned by the last failed attempt.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// WebhookQueueStore holds the webhooks queued by a WebhookQueue.
+type WebhookQueueStore interface {
+	// Push adds a webhook to the queue.
+	Push(ctx context.Context, webhook QueuedWebhook) error
+	// Pop takes the oldest webhook that is due, so that no other worker takes it, or returns nil if none are due.
+	Pop(ctx context.Context) (*QueuedWebhook, error)
+	// Ack removes a webhook taken by Pop once it has been processed.
+	Ack(ctx context.Context, id string) error
+	// Retry returns a webhook taken by Pop to the queue, to be processed again at its NextAttemptAt.
+	Retry(ctx context.Context, webhook QueuedWebhook) error
+	// Bury moves a webhook taken by Pop to the dead letters.
+	Bury(ctx context.Context, webhook QueuedWebhook) error
+
+	// DeadLetters lists the webhooks that were buried, oldest first.
+	DeadLetters(ctx context.Context) ([]QueuedWebhook, error)
+	// Replay moves a dead letter back to the queue, to be processed straight away. Its attempts are reset.
+	Replay(ctx context.Context, id string) error
+	// Discard deletes a dead letter.
+	Discard(ctx context.Context, id string) error
+}
+
+// WebhookQueueOptions configures a WebhookQueue.
+type WebhookQueueOptions struct {
+	// Workers is how many webhooks are processed at once. Defaults to 4.
+	Workers int
+	// MaxAttempts is how many times a webhook is processed before it is moved to the dead letters. Defaults to 5.
+	MaxAttempts int
+	// BaseDelay is the delay before a webhook's first retry, which doubles with each subsequent retry. Defaults to 1s.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries. Defaults to 5m.
+	MaxDelay time.Duration
+	// PollInterval is how often idle workers check for webhooks that are due to be retried. Defaults to 1s.
+	PollInterval time.Duration
+	// OnError, if set, is called with the errors the workers get from the store, which have no caller to be returned
+	// to. A webhook whose Ack failed may be processed again, and one whose Retry or Bury failed is left processing,
+	// which a FileWebhookQueueStore returns to the queue when it is next created.
+	OnError func(err error)
+}
+
+// WebhookQueue processes webhooks asynchronously, so that the receiver can respond to Akahu straight away rather
+// than processing the webhook within Akahu's delivery timeout. Webhooks are dispatched by a pool of workers, with
+// retries and backoff, and webhooks that keep failing are moved to the store's dead letters to be inspected and replayed.
+type WebhookQueue struct {
+	store      WebhookQueueStore
+	dispatcher *WebhookDispatcher
+	opts       WebhookQueueOptions
+
+	notify chan struct{}
+}
+
+// NewWebhookQueue creates a WebhookQueue that holds webhooks in store and processes them with dispatcher.
+// Set it as WebhookHandler.Queue, and call Run to start processing.
+func NewWebhookQueue(store WebhookQueueStore, dispatcher *WebhookDispatcher, opts WebhookQueueOptions) *WebhookQueue {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultQueueWorkers
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultQueueMaxAttempts
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = defaultQueueBaseDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = defaultQueueMaxDelay
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultQueuePollInterval
+	}
+
+	return &WebhookQueue{
+		store:      store,
+		dispatcher: dispatcher,
+		opts:       opts,
+		notify:     make(chan struct{}, 1),
+	}
+}
+
+// Enqueue adds a webhook to the queue, returning once it has been stored.
+func (q *WebhookQueue) Enqueue(ctx context.Context, payload WebHookEventPayload) error {
+	id, err := newQueuedWebhookID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = q.store.Push(ctx, QueuedWebhook{
+		ID:            id,
+		Payload:       payload,
+		EnqueuedAt:    now,
+		NextAttemptAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	q.wake()
+	return nil
+}
+
+// DeadLetters lists the webhooks that failed every attempt.
+func (q *WebhookQueue) DeadLetters(ctx context.Context) ([]QueuedWebhook, error) {
+	return q.store.DeadLetters(ctx)
+}
+
+// Replay moves a dead letter back to the queue to be processed again, e.g. once the bug that caused it to fail has been fixed.
+func (q *WebhookQueue) Replay(ctx context.Context, id string) error {
+	if err := q.store.Replay(ctx, id); err != nil {
+		return err
+	}
+
+	q.wake()
+	return nil
+}
+
+// Run processes webhooks until ctx is done, then waits for the webhooks being processed to finish and returns ctx.Err().
+func (q *WebhookQueue) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < q.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (q *WebhookQueue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		webhook, err := q.store.Pop(ctx)
+		if err != nil && ctx.Err() == nil {
+			q.report(fmt.Errorf("akahu: taking queued webhook: %w", err))
+		}
+		if err == nil && webhook != nil {
+			q.process(ctx, *webhook)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-q.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *WebhookQueue) process(ctx context.Context, webhook QueuedWebhook) {
+	err := q.dispatch(ctx, webhook.Payload)
+	if err == nil {
+		if err := q.store.Ack(context.Background(), webhook.ID); err != nil {
+			q.report(fmt.Errorf("akahu: acknowledging queued webhook %s: %w", webhook.ID, err))
+		}
+		return
+	}
+
+	// The store is updated with a fresh context, so that a webhook being processed during shutdown isn't lost.
+	storeCtx := context.Background()
+	if ctx.Err() != nil {
+		// Interrupted by shutdown, which doesn't count as an attempt.
+		if err := q.store.Retry(storeCtx, webhook); err != nil {
+			q.report(fmt.Errorf("akahu: returning interrupted webhook %s to the queue: %w", webhook.ID, err))
+		}
+		return
+	}
+
+	webhook.Attempts++
+	webhook.LastError = err.Error()
+	if webhook.Attempts >= q.opts.MaxAttempts {
+		if err := q.store.Bury(storeCtx, webhook); err != nil {
+			q.report(fmt.Errorf("akahu: burying queued webhook %s: %w", webhook.ID, err))
+		}
+		return
+	}
+
+	webhook.NextAttemptAt = time.Now().UTC().Add(q.backoff(webhook.Attempts))
+	if err := q.store.Retry(storeCtx, webhook); err != nil {
+		q.report(fmt.Errorf("akahu: retrying queued webhook %s: %w", webhook.ID, err))
+	}
+}
+
+func (q *WebhookQueue) report(err error) {
+	if q.opts.OnError != nil {
+		q.opts.OnError(err)
+	}
+}
+
+// dispatch turns a panic in a handler into an error, so that the webhook is retried and then buried like any other
+// failure, rather than crashing the process with the webhook left processing.
+func (q *WebhookQueue) dispatch(ctx context.Context, payload WebHookEventPayload) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("akahu: webhook handler panicked: %v", r)
+		}
+	}()
+
+	return q.dispatcher.Dispatch(ctx, payload)
+}
+
+func (q *WebhookQueue) backoff(attempts int) time.Duration {
+	delay := q.opts.BaseDelay << (attempts - 1)
+	if delay > q.opts.MaxDelay || delay <= 0 {
+		delay = q.opts.MaxDelay
+	}
+
+	// Jitter spreads out webhooks that failed together.
+	return delay/2 + time.Duration(mathrand.Int63n(int64(delay/2)+1))
+}
+
+// wake signals an idle worker that a webhook is ready.
+func (q *WebhookQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// newQueuedWebhookID returns an ID that sorts in the order webhooks were queued.
+func newQueuedWebhookID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%020d-%s", time.Now().UnixNano(), hex.EncodeToString(b)), nil
+}
+
+// MemoryWebhookQueueStore is a WebhookQueueStore that holds webhooks in memory. Queued webhooks are lost if the
+// process exits, use a FileWebhookQueueStore to keep them.
+type MemoryWebhookQueueStore struct {
+	mu         sync.Mutex
+	queued     map[string]QueuedWebhook
+	processing map[string]QueuedWebhook
+	dead       map[string]QueuedWebhook
+}
+
+// NewMemoryWebhookQueueStore creates an empty MemoryWebhookQueueStore.
+func NewMemoryWebhookQueueStore() *MemoryWebhookQueueStore {
+	return &MemoryWebhookQueueStore{
+		queued:     map[string]QueuedWebhook{},
+		processing: map[string]QueuedWebhook{},
+		dead:       map[string]QueuedWebhook{},
+	}
+}
+
+func (s *MemoryWebhookQueueStore) Push(_ context.Context, webhook QueuedWebhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queued[webhook.ID] = webhook
+	return nil
+}
+
+func (s *MemoryWebhookQueueStore) Pop(_ context.Context) (*QueuedWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, id := range sortedQueuedIDs(s.queued) {
+		webhook := s.queued[id]
+		if webhook.NextAttemptAt.After(now) {
+			continue
+		}
+
+		delete(s.queued, id)
+		s.processing[id] = webhook
+		return &webhook, nil
+	}
+
+	return nil, nil
+}
+
+func (s *MemoryWebhookQueueStore) Ack(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.processing, id)
+	return nil
+}
+
+func (s *MemoryWebhookQueueStore) Retry(_ context.Context, webhook QueuedWebhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.processing, webhook.ID)
+	s.queued[webhook.ID] = webhook
+	return nil
+}
+
+func (s *MemoryWebhookQueueStore) Bury(_ context.Context, webhook QueuedWebhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.processing, webhook.ID)
+	s.dead[webhook.ID] = webhook
+	return nil
+}
+
+func (s *MemoryWebhookQueueStore) DeadLetters(_ context.Context) ([]QueuedWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dead := make([]QueuedWebhook, 0, len(s.dead))
+	for _, id := range sortedQueuedIDs(s.dead) {
+		dead = append(dead, s.dead[id])
+	}
+
+	return dead, nil
+}
+
+func (s *MemoryWebhookQueueStore) Replay(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.dead[id]
+	if !ok {
+		return ErrQueuedWebhookNotFound
+	}
+
+	delete(s.dead, id)
+	s.queued[id] = replayed(webhook)
+	return nil
+}
+
+func (s *MemoryWebhookQueueStore) Discard(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.dead[id]; !ok {
+		return ErrQueuedWebhookNotFound
+	}
+
+	delete(s.dead, id)
+	return nil
+}
+
+func sortedQueuedIDs(webhooks map[string]QueuedWebhook) []string {
+	ids := make([]string, 0, len(webhooks))
+	for id := range webhooks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// replayed resets a dead letter's attempts, so that it is processed straight away with its full number of attempts.
+func replayed(webhook QueuedWebhook) QueuedWebhook {
+	webhook.Attempts = 0
+	webhook.NextAttemptAt = time.Now().UTC()
+	return webhook
+}
+
+const (
+	queuedDir     = "queued"
+	processingDir = "processing"
+	deadDir       = "dead"
+)
+
+// FileWebhookQueueStore is a WebhookQueueStore that keeps each webhook in its own file, so that queued webhooks
+// survive a restart. Files are moved between the queued, processing and dead subdirectories of the store's directory
+// by renaming them, which is atomic. The names of queued files start with when the webhook is due, so that Pop only
+// reads the file it takes.
+//
+// Only one process should use the directory at a time, as webhooks left processing by a crash are returned to the
+// queue when the store is created.
+type FileWebhookQueueStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileWebhookQueueStore creates a FileWebhookQueueStore that keeps its files in dir, which is created if it doesn't
+// exist. Webhooks that were being processed when the previous process exited are returned to the queue.
+func NewFileWebhookQueueStore(dir string) (*FileWebhookQueueStore, error) {
+	for _, sub := range []string{queuedDir, processingDir, deadDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &FileWebhookQueueStore{dir: dir}
+
+	interrupted, err := s.ids(processingDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range interrupted {
+		// They were already due, so they are queued to be processed straight away.
+		if err := os.Rename(s.path(processingDir, id), s.queuedPath(id, time.Time{})); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *FileWebhookQueueStore) Push(_ context.Context, webhook QueuedWebhook) error {
+	return s.write(s.queuedPath(webhook.ID, webhook.NextAttemptAt), webhook)
+}
+
+// Pop takes the oldest due webhook. A file that can't be decoded is moved to the dead letters, so that it isn't
+// read again by every poll, and the error is returned.
+func (s *FileWebhookQueueStore) Pop(_ context.Context) (*QueuedWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, queuedDir))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		next     string
+		nextName string
+		now      = time.Now()
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		id, dueAt := parseQueuedName(name)
+		if dueAt.After(now) {
+			continue
+		}
+		if nextName == "" || id < next {
+			next, nextName = id, name
+		}
+	}
+	if nextName == "" {
+		return nil, nil
+	}
+
+	processing := s.path(processingDir, next)
+	if err := os.Rename(filepath.Join(s.dir, queuedDir, nextName), processing); err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.read(processingDir, next)
+	if err != nil {
+		if buryErr := os.Rename(processing, s.path(deadDir, next)); buryErr != nil {
+			return nil, fmt.Errorf("%w, and it could not be moved to the dead letters: %v", err, buryErr)
+		}
+		return nil, fmt.Errorf("%w, it was moved to the dead letters", err)
+	}
+
+	return webhook, nil
+}
+
+func (s *FileWebhookQueueStore) Ack(_ context.Context, id string) error {
+	err := os.Remove(s.path(processingDir, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileWebhookQueueStore) Retry(ctx context.Context, webhook QueuedWebhook) error {
+	if err := s.write(s.queuedPath(webhook.ID, webhook.NextAttemptAt), webhook); err != nil {
+		return err
+	}
+	return s.Ack(ctx, webhook.ID)
+}
+
+func (s *FileWebhookQueueStore) Bury(ctx context.Context, webhook QueuedWebhook) error {
+	if err := s.write(s.path(deadDir, webhook.ID), webhook); err != nil {
+		return err
+	}
+	return s.Ack(ctx, webhook.ID)
+}
+
+// DeadLetters lists the buried webhooks. Files that couldn't be decoded are listed with only their ID, and the
+// error as their LastError.
+func (s *FileWebhookQueueStore) DeadLetters(_ context.Context) ([]QueuedWebhook, error) {
+	ids, err := s.ids(deadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dead := make([]QueuedWebhook, 0, len(ids))
+	for _, id := range ids {
+		webhook, err := s.read(deadDir, id)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			webhook = &QueuedWebhook{ID: id, LastError: err.Error()}
+		}
+		dead = append(dead, *webhook)
+	}
+
+	return dead, nil
+}
+
+func (s *FileWebhookQueueStore) Replay(_ context.Context, id string) error {
+	webhook, err := s.read(deadDir, id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrQueuedWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	replay := replayed(*webhook)
+	if err := s.write(s.queuedPath(replay.ID, replay.NextAttemptAt), replay); err != nil {
+		return err
+	}
+	return os.Remove(s.path(deadDir, id))
+}
+
+func (s *FileWebhookQueueStore) Discard(_ context.Context, id string) error {
+	err := os.Remove(s.path(deadDir, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrQueuedWebhookNotFound
+	}
+	return err
+}
+
+func (s *FileWebhookQueueStore) path(sub, id string) string {
+	// IDs come from newQueuedWebhookID, but Replay and Discard take them from callers.
+	return filepath.Join(s.dir, sub, filepath.Base(id)+".json")
+}
+
+// queuedPath names a queued webhook's file after when it is due, then its ID.
+func (s *FileWebhookQueueStore) queuedPath(id string, dueAt time.Time) string {
+	var due int64
+	if !dueAt.IsZero() {
+		due = dueAt.UnixNano()
+	}
+	return filepath.Join(s.dir, queuedDir, fmt.Sprintf("%020d_%s.json", due, filepath.Base(id)))
+}
+
+// parseQueuedName returns the ID and due time in a queued file's name. Names without a due time are due straight away.
+func parseQueuedName(name string) (string, time.Time) {
+	name = strings.TrimSuffix(name, ".json")
+	due, id, ok := strings.Cut(name, "_")
+	if !ok {
+		return name, time.Time{}
+	}
+
+	nanos, err := strconv.ParseInt(due, 10, 64)
+	if err != nil {
+		return name, time.Time{}
+	}
+
+	return id, time.Unix(0, nanos)
+}
+
+// ids lists the IDs of the webhooks in a subdirectory, oldest first.
+func (s *FileWebhookQueueStore) ids(sub string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, ".json") {
+			ids = append(ids, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+func (s *FileWebhookQueueStore) read(sub, id string) (*QueuedWebhook, error) {
+	data, err := os.ReadFile(s.path(sub, id))
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook QueuedWebhook
+	if err := json.Unmarshal(data, &webhook); err != nil {
+		return nil, fmt.Errorf("akahu: reading queued webhook %s: %w", id, err)
+	}
+
+	return &webhook, nil
+}
+
+// write saves a webhook to path with writeFileAtomic, so that a crash never leaves a partially written webhook in the
+// queue. The temporary file doesn't end in .json, so it is never taken for a queued webhook.
+func (s *FileWebhookQueueStore) write(path string, webhook QueuedWebhook) error {
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
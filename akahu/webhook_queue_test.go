@@ -0,0 +1,322 @@
+package akahu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookQueue(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) WebhookQueueStore
+	}{
+		{
+			name: "with memory store",
+			store: func(t *testing.T) WebhookQueueStore {
+				return NewMemoryWebhookQueueStore()
+			},
+		},
+		{
+			name: "with file store",
+			store: func(t *testing.T) WebhookQueueStore {
+				store, err := NewFileWebhookQueueStore(t.TempDir())
+				if err != nil {
+					t.Fatalf("NewFileWebhookQueueStore returned err %v", err)
+				}
+				return store
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := map[string]int{}
+			poisoned := true
+
+			dispatcher := NewWebhookDispatcher()
+			dispatcher.HandleFunc(Transaction, func(ctx context.Context, payload WebHookEventPayload) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				attempts[payload.State]++
+				switch {
+				case payload.State == "flaky" && attempts[payload.State] < 3:
+					return errors.New("database unavailable")
+				case payload.State == "poison" && poisoned:
+					return errors.New("unexpected payload")
+				}
+				return nil
+			})
+
+			store := test.store(t)
+			queue := NewWebhookQueue(store, dispatcher, WebhookQueueOptions{
+				Workers:      2,
+				MaxAttempts:  3,
+				BaseDelay:    time.Millisecond,
+				MaxDelay:     5 * time.Millisecond,
+				PollInterval: 5 * time.Millisecond,
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- queue.Run(ctx)
+			}()
+			defer func() {
+				cancel()
+				if err := <-done; err != context.Canceled {
+					t.Errorf("expected Run to return %v, actual %v", context.Canceled, err)
+				}
+			}()
+
+			for _, state := range []string{"ok", "flaky", "poison"} {
+				payload := WebHookEventPayload{WebhookType: Transaction, WebhookCode: TransactionDefaultUpdate, State: state}
+				if err := queue.Enqueue(ctx, payload); err != nil {
+					t.Fatalf("Enqueue returned err %v", err)
+				}
+			}
+
+			dead := waitForDeadLetters(t, queue, 1)
+			if dead[0].Payload.State != "poison" || dead[0].Attempts != 3 || dead[0].LastError != "unexpected payload" {
+				t.Fatalf("unexpected dead letter %+v", dead[0])
+			}
+
+			mu.Lock()
+			if attempts["ok"] != 1 || attempts["flaky"] != 3 || attempts["poison"] != 3 {
+				t.Fatalf("unexpected attempts %v", attempts)
+			}
+			poisoned = false
+			mu.Unlock()
+
+			if err := queue.Replay(ctx, dead[0].ID); err != nil {
+				t.Fatalf("Replay returned err %v", err)
+			}
+			waitForDeadLetters(t, queue, 0)
+			waitFor(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return attempts["poison"] == 4
+			})
+
+			if err := queue.Replay(ctx, dead[0].ID); err != ErrQueuedWebhookNotFound {
+				t.Fatalf("expected err %v replaying twice, actual %v", ErrQueuedWebhookNotFound, err)
+			}
+		})
+	}
+}
+
+func TestWebhookQueue_RecoversPanics(t *testing.T) {
+	dispatcher := NewWebhookDispatcher()
+	dispatcher.HandleFunc(Transaction, func(ctx context.Context, payload WebHookEventPayload) error {
+		panic("unexpected payload")
+	})
+
+	queue := NewWebhookQueue(NewMemoryWebhookQueueStore(), dispatcher, WebhookQueueOptions{
+		Workers:      1,
+		MaxAttempts:  2,
+		BaseDelay:    time.Millisecond,
+		MaxDelay:     time.Millisecond,
+		PollInterval: time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = queue.Run(ctx) }()
+
+	if err := queue.Enqueue(ctx, WebHookEventPayload{WebhookType: Transaction, State: "user_1"}); err != nil {
+		t.Fatalf("Enqueue returned err %v", err)
+	}
+
+	// The panic is retried, then buried, and the worker keeps running.
+	dead := waitForDeadLetters(t, queue, 1)
+	if dead[0].Attempts != 2 || !strings.HasPrefix(dead[0].LastError, "akahu: webhook handler panicked: ") {
+		t.Fatalf("unexpected dead letter %+v", dead[0])
+	}
+}
+
+// failingAckStore is a WebhookQueueStore whose Ack always fails.
+type failingAckStore struct {
+	*MemoryWebhookQueueStore
+}
+
+func (s failingAckStore) Ack(context.Context, string) error {
+	return errors.New("disk full")
+}
+
+func TestWebhookQueue_OnError(t *testing.T) {
+	errs := make(chan error, 1)
+	queue := NewWebhookQueue(failingAckStore{NewMemoryWebhookQueueStore()}, NewWebhookDispatcher(), WebhookQueueOptions{
+		Workers:      1,
+		PollInterval: time.Millisecond,
+		OnError:      func(err error) { errs <- err },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = queue.Run(ctx) }()
+
+	if err := queue.Enqueue(ctx, WebHookEventPayload{WebhookType: Transaction, State: "user_1"}); err != nil {
+		t.Fatalf("Enqueue returned err %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if !strings.HasPrefix(err.Error(), "akahu: acknowledging queued webhook ") || !strings.HasSuffix(err.Error(), ": disk full") {
+			t.Fatalf("unexpected err %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected OnError to be called")
+	}
+}
+
+func TestWebhookQueueStore_Discard(t *testing.T) {
+	fileStore, err := NewFileWebhookQueueStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileWebhookQueueStore returned err %v", err)
+	}
+
+	for _, store := range []WebhookQueueStore{NewMemoryWebhookQueueStore(), fileStore} {
+		ctx := context.Background()
+		webhook := QueuedWebhook{ID: "1", NextAttemptAt: time.Now()}
+		_ = store.Push(ctx, webhook)
+		popped, _ := store.Pop(ctx)
+		if popped == nil || popped.ID != "1" {
+			t.Fatalf("expected webhook 1 to be popped, actual %+v", popped)
+		}
+		_ = store.Bury(ctx, *popped)
+
+		if err := store.Discard(ctx, "1"); err != nil {
+			t.Fatalf("Discard returned err %v", err)
+		}
+		if dead, _ := store.DeadLetters(ctx); len(dead) != 0 {
+			t.Fatalf("expected no dead letters, actual %+v", dead)
+		}
+		if err := store.Discard(ctx, "1"); err != ErrQueuedWebhookNotFound {
+			t.Fatalf("expected err %v, actual %v", ErrQueuedWebhookNotFound, err)
+		}
+	}
+}
+
+func TestFileWebhookQueueStore_RecoversInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, _ := NewFileWebhookQueueStore(dir)
+	_ = store.Push(ctx, QueuedWebhook{ID: "1", NextAttemptAt: time.Now()})
+	_ = store.Push(ctx, QueuedWebhook{ID: "2", NextAttemptAt: time.Now().Add(time.Hour)})
+
+	popped, _ := store.Pop(ctx)
+	if popped == nil || popped.ID != "1" {
+		t.Fatalf("expected webhook 1 to be popped, actual %+v", popped)
+	}
+	if popped, _ := store.Pop(ctx); popped != nil {
+		t.Fatalf("expected webhook 2 not to be due, actual %+v", popped)
+	}
+
+	// The process exits while webhook 1 is being processed.
+	store, err := NewFileWebhookQueueStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileWebhookQueueStore returned err %v", err)
+	}
+
+	popped, _ = store.Pop(ctx)
+	if popped == nil || popped.ID != "1" {
+		t.Fatalf("expected webhook 1 to be popped again, actual %+v", popped)
+	}
+}
+
+func TestFileWebhookQueueStore_BuriesCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, _ := NewFileWebhookQueueStore(dir)
+	_ = store.Push(ctx, QueuedWebhook{ID: "2", NextAttemptAt: time.Now()})
+	if err := os.WriteFile(filepath.Join(dir, queuedDir, "00000000000000000000_1.json"), []byte("{"), 0o600); err != nil {
+		t.Fatalf("WriteFile returned err %v", err)
+	}
+
+	// The corrupt webhook is the oldest, so it is taken first, reported and moved to the dead letters.
+	popped, err := store.Pop(ctx)
+	if popped != nil || err == nil || !strings.HasPrefix(err.Error(), "akahu: reading queued webhook 1: ") {
+		t.Fatalf("expected err reading webhook 1, actual %+v %v", popped, err)
+	}
+
+	popped, err = store.Pop(ctx)
+	if err != nil || popped == nil || popped.ID != "2" {
+		t.Fatalf("expected webhook 2 to be popped, actual %+v %v", popped, err)
+	}
+
+	dead, err := store.DeadLetters(ctx)
+	if err != nil || len(dead) != 1 || dead[0].ID != "1" || dead[0].LastError == "" {
+		t.Fatalf("expected corrupt webhook 1 in the dead letters, actual %+v %v", dead, err)
+	}
+	if err := store.Discard(ctx, "1"); err != nil {
+		t.Fatalf("Discard returned err %v", err)
+	}
+}
+
+func TestWebhookHandler_Queue(t *testing.T) {
+	publicKeyJson, _ := json.Marshal(testWebhookPublicKey)
+	client := setupClient(t, fmt.Sprintf(itemResponseJson, publicKeyJson), http.MethodGet, http.StatusOK)
+
+	dispatcher := NewWebhookDispatcher()
+	dispatcher.HandleFunc(Account, func(ctx context.Context, payload WebHookEventPayload) error {
+		t.Fatalf("expected webhook to be queued rather than dispatched")
+		return nil
+	})
+
+	store := NewMemoryWebhookQueueStore()
+	handler := NewWebhookHandler(client, dispatcher)
+	handler.Queue = NewWebhookQueue(store, dispatcher, WebhookQueueOptions{})
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(testWebhookBody))
+	req.Header.Set("X-Akahu-Signature", testWebhookSignature)
+	req.Header.Set("X-Akahu-Signing-Key", "1")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, actual %d", http.StatusOK, rec.Code)
+	}
+
+	queued, _ := store.Pop(context.Background())
+	if queued == nil || queued.Payload.ItemId != "acc_1111111111111111111111111" || queued.Attempts != 0 {
+		t.Fatalf("unexpected queued webhook %+v", queued)
+	}
+}
+
+func waitForDeadLetters(t *testing.T, queue *WebhookQueue, n int) []QueuedWebhook {
+	t.Helper()
+
+	var dead []QueuedWebhook
+	waitFor(t, func() bool {
+		dead, _ = queue.DeadLetters(context.Background())
+		return len(dead) == n
+	})
+
+	return dead
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"encoding/pem"
 	"errors"
 	"net/http"
-	"path"
 	"time"
 )
 
@@ -35,6 +34,16 @@ const (
 	Income                  = "INCOME"
 )
 
+// Webhook codes of TRANSACTION webhooks.
+const (
+	// TransactionInitialUpdate is sent once the first transactions for an account have been fetched.
+	TransactionInitialUpdate = "INITIAL_UPDATE"
+	// TransactionDefaultUpdate is sent when new transactions are fetched for an account.
+	TransactionDefaultUpdate = "DEFAULT_UPDATE"
+	// TransactionDelete is sent when transactions are removed from an account.
+	TransactionDelete = "DELETE"
+)
+
 type WebhookEventStatus string
 
 const (
@@ -44,12 +53,13 @@ const (
 )
 
 type WebhookResponse struct {
-	Id           string    `json:"_id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	LastCalledAt time.Time `json:"last_called_at"`
-	State        string    `json:"state"`
-	Url          string    `json:"url"`
+	Id           WebhookID   `json:"_id"`
+	Type         WebhookType `json:"type"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+	LastCalledAt time.Time   `json:"last_called_at"`
+	State        string      `json:"state"`
+	Url          string      `json:"url"`
 }
 
 type WebhookSubscribeRequest struct {
@@ -59,18 +69,28 @@ type WebhookSubscribeRequest struct {
 
 type WebhookSubscribeResponse struct {
 	successResponse
-	ItemId *string `json:"item_id"`
+	ItemId *WebhookID `json:"item_id"`
 }
 
 type WebHookEventPayload struct {
 	successResponse
 	WebhookType `json:"webhook_type"`
 	WebhookCode string `json:"webhook_code"`
+	State       string `json:"state,omitempty"`
+	ItemId      string `json:"item_id,omitempty"`
+
+	// NewTransactions and NewTransactionIds are set on TRANSACTION webhooks with the INITIAL_UPDATE and DEFAULT_UPDATE codes.
+	NewTransactions   int             `json:"new_transactions,omitempty"`
+	NewTransactionIds []TransactionID `json:"new_transaction_ids,omitempty"`
+	// RemovedTransactions is set on TRANSACTION webhooks with the DELETE code.
+	RemovedTransactions []TransactionID `json:"removed_transactions,omitempty"`
+	// UpdatedFields is set on ACCOUNT webhooks with the UPDATE code.
+	UpdatedFields []string `json:"updated_fields,omitempty"`
 }
 
 type WebHookEventResponse struct {
 	Id           string              `json:"_id"`
-	Hook         string              `json:"hook"`
+	Hook         WebhookID           `json:"hook"`
 	Status       WebhookEventStatus  `json:"status"`
 	CreatedAt    time.Time           `json:"created_at"`
 	UpdatedAt    time.Time           `json:"updated_at"`
@@ -88,7 +108,7 @@ func (s *WebhooksService) List(ctx context.Context, userAccessToken string) ([]W
 	}
 
 	var webhooks collectionResponse[WebhookResponse]
-	res, err := s.client.do(ctx, r, &webhooks)
+	res, err := s.client.do(ctx, "akahu.Webhooks.List", r, &webhooks)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -100,13 +120,17 @@ func (s *WebhooksService) List(ctx context.Context, userAccessToken string) ([]W
 //
 // Akahu docs: https://developers.akahu.nz/reference/get_keys-id
 func (s *WebhooksService) GetPublicKey(ctx context.Context, id string) (*string, *APIResponse, error) {
-	r, err := s.client.newRequest(http.MethodGet, path.Join(publicKeyPath, id), nil, withBasicAuthRequestConfig())
+	if err := checkNotEmpty("id", id); err != nil {
+		return nil, nil, err
+	}
+
+	r, err := s.client.newRequest(http.MethodGet, joinPath(publicKeyPath, id), nil, withBasicAuthRequestConfig())
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var publicKey itemResponse[string]
-	res, err := s.client.do(ctx, r, &publicKey)
+	res, err := s.client.do(ctx, "akahu.Webhooks.GetPublicKey", r, &publicKey)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -115,11 +139,52 @@ func (s *WebhooksService) GetPublicKey(ctx context.Context, id string) (*string,
 }
 
 // ListEvents gets a list of webhook events that have been published to your application by Akahu within the 'start' and 'end' time range.
+// Every page of events is fetched, use ListEventsPage to fetch them a page at a time.
 //
 // Akahu docs: https://developers.akahu.nz/reference/get_webhook-events
 func (s *WebhooksService) ListEvents(ctx context.Context, userAccessToken, status string, startTime, endTime time.Time) ([]WebHookEventResponse, *APIResponse, error) {
-	params := paramsWithDateRange(startTime, endTime)
+	var (
+		events []WebHookEventResponse
+		cursor string
+	)
+	for {
+		page, res, err := s.ListEventsPage(ctx, userAccessToken, status, startTime, endTime, cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !res.Success {
+			return nil, res, nil
+		}
+
+		if events == nil {
+			events = page
+		} else {
+			events = append(events, page...)
+		}
+
+		if res.NextCursor == "" || res.NextCursor == cursor {
+			return events, res, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// ListEventsPage gets a page of the webhook events that have been published to your application by Akahu within the
+// 'start' and 'end' time range. Pass an empty cursor for the first page, then APIResponse.NextCursor until it is empty.
+//
+// Akahu docs: https://developers.akahu.nz/reference/get_webhook-events
+func (s *WebhooksService) ListEventsPage(ctx context.Context, userAccessToken, status string, startTime, endTime time.Time, cursor string) ([]WebHookEventResponse, *APIResponse, error) {
+	if err := checkNotEmpty("status", status); err != nil {
+		return nil, nil, err
+	}
+	params, err := paramsWithDateRange(startTime, endTime)
+	if err != nil {
+		return nil, nil, err
+	}
 	params.Add("status", status)
+	if cursor != "" {
+		params.Add("cursor", cursor)
+	}
 	encodedPath := pathWithParams(webhookEventsPath, params)
 
 	r, err := s.client.newRequest(http.MethodGet, encodedPath, nil, withTokenRequestConfig(userAccessToken))
@@ -128,7 +193,7 @@ func (s *WebhooksService) ListEvents(ctx context.Context, userAccessToken, statu
 	}
 
 	var events collectionResponse[WebHookEventResponse]
-	res, err := s.client.do(ctx, r, &events)
+	res, err := s.client.do(ctx, "akahu.Webhooks.ListEvents", r, &events)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -139,14 +204,14 @@ func (s *WebhooksService) ListEvents(ctx context.Context, userAccessToken, statu
 // Subscribe creates a new webhook subscription for the user.
 //
 // Akahu docs: https://developers.akahu.nz/reference/post_webhooks
-func (s *WebhooksService) Subscribe(ctx context.Context, userAccessToken string, body WebhookSubscribeRequest) (*string, *APIResponse, error) {
+func (s *WebhooksService) Subscribe(ctx context.Context, userAccessToken string, body WebhookSubscribeRequest) (*WebhookID, *APIResponse, error) {
 	r, err := s.client.newRequest(http.MethodPost, webhooksPath, body, withTokenRequestConfig(userAccessToken))
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var webhookSubscribe WebhookSubscribeResponse
-	res, err := s.client.do(ctx, r, &webhookSubscribe)
+	res, err := s.client.do(ctx, "akahu.Webhooks.Subscribe", r, &webhookSubscribe)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -157,14 +222,18 @@ func (s *WebhooksService) Subscribe(ctx context.Context, userAccessToken string,
 // Unsubscribe deletes a webhook subscription that your application has previously created for the user.
 //
 // Akahu docs: https://developers.akahu.nz/reference/delete_webhooks-id
-func (s *WebhooksService) Unsubscribe(ctx context.Context, userAccessToken, id string) (bool, *APIResponse, error) {
-	r, err := s.client.newRequest(http.MethodDelete, path.Join(webhooksPath, id), nil, withTokenRequestConfig(userAccessToken))
+func (s *WebhooksService) Unsubscribe(ctx context.Context, userAccessToken string, id WebhookID) (bool, *APIResponse, error) {
+	if err := checkID("webhookID", id); err != nil {
+		return false, nil, err
+	}
+
+	r, err := s.client.newRequest(http.MethodDelete, joinPath(webhooksPath, string(id)), nil, withTokenRequestConfig(userAccessToken))
 	if err != nil {
 		return false, nil, err
 	}
 
 	var webhookDelete successResponse
-	res, err := s.client.do(ctx, r, &webhookDelete)
+	res, err := s.client.do(ctx, "akahu.Webhooks.Unsubscribe", r, &webhookDelete)
 	if err != nil {
 		return false, nil, err
 	}
@@ -172,6 +241,80 @@ func (s *WebhooksService) Unsubscribe(ctx context.Context, userAccessToken, id s
 	return webhookDelete.Success, res, nil
 }
 
+// SubscriptionChanges are the changes made by WebhooksService.EnsureSubscriptions.
+type SubscriptionChanges struct {
+	// Subscribed are the webhooks that were created. Only their Id, Type and State are set.
+	Subscribed []WebhookResponse
+	// Unsubscribed are the webhooks that were deleted.
+	Unsubscribed []WebhookResponse
+	// Unchanged are the existing webhooks that were already in the desired set.
+	Unchanged []WebhookResponse
+}
+
+// EnsureSubscriptions makes the user's webhook subscriptions match desired. Webhooks are identified by their type
+// and state: desired webhooks that the user isn't subscribed to are subscribed, and subscriptions that aren't
+// desired, including duplicates, are unsubscribed. New subscriptions are made before old ones are removed, so
+// there is no gap in delivery.
+//
+// If Akahu returns an unsuccessful response, no further changes are made and the response is returned with the
+// changes made so far.
+func (s *WebhooksService) EnsureSubscriptions(ctx context.Context, userAccessToken string, desired []WebhookSubscribeRequest) (*SubscriptionChanges, *APIResponse, error) {
+	existing, res, err := s.List(ctx, userAccessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !res.Success {
+		return nil, res, nil
+	}
+
+	changes := &SubscriptionChanges{}
+	wanted := map[WebhookSubscribeRequest]bool{}
+	for _, d := range desired {
+		wanted[d] = true
+	}
+
+	var extra []WebhookResponse
+	for _, webhook := range existing {
+		key := WebhookSubscribeRequest{WebhookType: webhook.Type, State: webhook.State}
+		if wanted[key] {
+			// Any further subscriptions with the same type and state are duplicates.
+			delete(wanted, key)
+			changes.Unchanged = append(changes.Unchanged, webhook)
+		} else {
+			extra = append(extra, webhook)
+		}
+	}
+
+	for _, d := range desired {
+		if !wanted[d] {
+			continue
+		}
+		delete(wanted, d)
+
+		id, res, err := s.Subscribe(ctx, userAccessToken, d)
+		if err != nil {
+			return changes, nil, err
+		}
+		if !res.Success || id == nil {
+			return changes, res, nil
+		}
+		changes.Subscribed = append(changes.Subscribed, WebhookResponse{Id: *id, Type: d.WebhookType, State: d.State})
+	}
+
+	for _, webhook := range extra {
+		_, res, err := s.Unsubscribe(ctx, userAccessToken, webhook.Id)
+		if err != nil {
+			return changes, nil, err
+		}
+		if !res.Success {
+			return changes, res, nil
+		}
+		changes.Unsubscribed = append(changes.Unsubscribed, webhook)
+	}
+
+	return changes, res, nil
+}
+
 // ValidateWebhookSignature validates the payload of a Akahu webhook request against private RSA key held by Akahu.
 // The public key can be obtained via the WebhooksService.GetPublicKey method.
 // The signature is a base64 encoded string that is included in the 'X-Akahu-Signature' header of the request.
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"path"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,7 +43,7 @@ func TestWebhooksService_Unsubscribe(t *testing.T) {
 				testTokenRequestHeaders(t, r, "app_token_123", "user_token_1")
 			})
 
-			actual, res, err := client.Webhooks.Unsubscribe(context.TODO(), "user_token_1", "id_1")
+			actual, res, err := client.Webhooks.Unsubscribe(context.TODO(), "user_token_1", "hook_1")
 			testClientResponse(t, test.expected, actual, err)
 			testClientAPIResponse(t, test.expectedAPIResponse, res, err)
 		})
@@ -48,14 +51,14 @@ func TestWebhooksService_Unsubscribe(t *testing.T) {
 }
 
 func TestWebhooksService_Subscribe(t *testing.T) {
-	expectedId := "hook_1111111111111111111111111"
+	expectedId := WebhookID("hook_1111111111111111111111111")
 
 	tests := []struct {
 		name                string
 		body                WebhookSubscribeRequest
 		jsonResponse        string
 		statusCode          int
-		expected            *string
+		expected            *WebhookID
 		expectedAPIResponse *APIResponse
 	}{
 		{
@@ -104,7 +107,7 @@ func TestWebhooksService_Subscribe(t *testing.T) {
 }
 
 func TestAccountsService_List(t *testing.T) {
-	webhookJson := "{ \"_id\": \"hook_1111111111111111111111111\", \"created_at\": \"2020-04-08T23:15:39.917Z\", \"updated_at\": \"2020-04-09T23:15:39.917Z\", \"last_called_at\": \"2020-04-10T23:15:39.917Z\", \"state\": \"foobarbaz\", \"url\": \"https://webhooks.myapp.com/akahu\" }"
+	webhookJson := "{ \"_id\": \"hook_1111111111111111111111111\", \"type\": \"TRANSACTION\", \"created_at\": \"2020-04-08T23:15:39.917Z\", \"updated_at\": \"2020-04-09T23:15:39.917Z\", \"last_called_at\": \"2020-04-10T23:15:39.917Z\", \"state\": \"foobarbaz\", \"url\": \"https://webhooks.myapp.com/akahu\" }"
 
 	createdAt, _ := time.Parse(time.RFC3339, "2020-04-08T23:15:39.917Z")
 	updatedAt, _ := time.Parse(time.RFC3339, "2020-04-09T23:15:39.917Z")
@@ -131,6 +134,7 @@ func TestAccountsService_List(t *testing.T) {
 			expected: []WebhookResponse{
 				{
 					Id:           "hook_1111111111111111111111111",
+					Type:         Transaction,
 					CreatedAt:    createdAt,
 					UpdatedAt:    updatedAt,
 					LastCalledAt: lastFailedAt,
@@ -296,11 +300,14 @@ func TestWebhooksService_GetEvents(t *testing.T) {
 	}
 }
 
-func TestValidateWebhookSignature(t *testing.T) {
-	validPub := "-----BEGIN RSA PUBLIC KEY-----\nMIIBCgKCAQEA1YWQaS5H27EvO3JNOH9nrl9SSSQspFWvoYy/jk9Z/4UhsXPg9S8s\ncXKPSVsZb78DXQs8EZDQBHWlVU1VKxtP7fL8EW0bcer0HIuwxKIYMP9IHdmbzOOg\nLJC8l2YNn7FqUKE1ltJgLct4UqyTF11jQdKHfhBV9DXtUP9vaFNfFzK1zEwKGggD\nsVkwFyna7UoW37l5ynV0BPTaVXZ6sVWoyvxjorcLqjUBCgIcGyHXkAxElsPSBRbE\nkydSvePKhe06tn6Ng+PPPJUIKzKMdB3cjKmi5Gsf7JIKRFDoY35oZsoYIRwsgujS\n9uFIlDoe0N44XuyXBtLnO2DrJ2yVKkUl/QIDAQAB\n-----END RSA PUBLIC KEY-----"
-	validSig := "FFcDepzALfLqD2Ljua+A1l3eZXHgUpTLWhGQC9OfYeWZX09JwF41F+T/lnKS/P8wP9Ox5eKFU8zhcnjLZ6qJUHgKtUbWUnepynM9bWi6WrkG36sbgsKeg0F0VTkM7SDFy93Vx0rNoJSCt/u87fNpOvEwIn7S7zoVlp5LfwXyispBVM3WpfMs/SDebj2CY3Ir/jqAUmNSTON0rn8+m4My6UKPBAwQCmlHzN4+1zjIJjvWc5Ez78mJUyEfx1qmM1VW2gbWYT3HuVjmGuNrPYQxuIHW6n7q31cKsa/OEVWixxzcUH3MtZvn/LeTMpKg2FmNNfVYUTkd67VxWDj179gm2A=="
-	body := "{\"webhook_type\":\"ACCOUNT\",\"webhook_code\":\"UPDATE\",\"state\":\"example state\",\"item_id\":\"acc_1111111111111111111111111\",\"updated_fields\":[\"balance\"]}"
+// A webhook body signed by testWebhookPublicKey.
+const (
+	testWebhookPublicKey = "-----BEGIN RSA PUBLIC KEY-----\nMIIBCgKCAQEA1YWQaS5H27EvO3JNOH9nrl9SSSQspFWvoYy/jk9Z/4UhsXPg9S8s\ncXKPSVsZb78DXQs8EZDQBHWlVU1VKxtP7fL8EW0bcer0HIuwxKIYMP9IHdmbzOOg\nLJC8l2YNn7FqUKE1ltJgLct4UqyTF11jQdKHfhBV9DXtUP9vaFNfFzK1zEwKGggD\nsVkwFyna7UoW37l5ynV0BPTaVXZ6sVWoyvxjorcLqjUBCgIcGyHXkAxElsPSBRbE\nkydSvePKhe06tn6Ng+PPPJUIKzKMdB3cjKmi5Gsf7JIKRFDoY35oZsoYIRwsgujS\n9uFIlDoe0N44XuyXBtLnO2DrJ2yVKkUl/QIDAQAB\n-----END RSA PUBLIC KEY-----"
+	testWebhookSignature = "FFcDepzALfLqD2Ljua+A1l3eZXHgUpTLWhGQC9OfYeWZX09JwF41F+T/lnKS/P8wP9Ox5eKFU8zhcnjLZ6qJUHgKtUbWUnepynM9bWi6WrkG36sbgsKeg0F0VTkM7SDFy93Vx0rNoJSCt/u87fNpOvEwIn7S7zoVlp5LfwXyispBVM3WpfMs/SDebj2CY3Ir/jqAUmNSTON0rn8+m4My6UKPBAwQCmlHzN4+1zjIJjvWc5Ez78mJUyEfx1qmM1VW2gbWYT3HuVjmGuNrPYQxuIHW6n7q31cKsa/OEVWixxzcUH3MtZvn/LeTMpKg2FmNNfVYUTkd67VxWDj179gm2A=="
+	testWebhookBody      = "{\"webhook_type\":\"ACCOUNT\",\"webhook_code\":\"UPDATE\",\"state\":\"example state\",\"item_id\":\"acc_1111111111111111111111111\",\"updated_fields\":[\"balance\"]}"
+)
 
+func TestValidateWebhookSignature(t *testing.T) {
 	tests := []struct {
 		name        string
 		publicKey   string
@@ -311,33 +318,33 @@ func TestValidateWebhookSignature(t *testing.T) {
 	}{
 		{
 			name:        "with valid key and signature",
-			publicKey:   validPub,
-			signature:   validSig,
-			body:        body,
+			publicKey:   testWebhookPublicKey,
+			signature:   testWebhookSignature,
+			body:        testWebhookBody,
 			expected:    true,
 			expectedErr: false,
 		},
 		{
 			name:        "with invalid signature",
-			publicKey:   validPub,
+			publicKey:   testWebhookPublicKey,
 			signature:   "g+A/e8ud9eDpQNva8RxE7h0Y+8HWIeR+Q6Lefv5R4D8HuPdpBtLgPzgkWPxmQo9mKHYm5iq3apGB95Gu/gFuO8XkVYYx80b0jR8rX6QWWfhBR7MkWIFD1paaKMwXJLfWiqP/3FbMSC7rrE/iOipuZaXRYWW6393jAgtinwzv4OsNYGWNFSeXiTkgcsMDH842t7YvX5GeeT5iT/iQxlflBpkXjmcmAaG2ba2YM/5iU7JjIrvwtZis2Vr196sA+lZKmsp8YnlZ9r++cfaPdAl48GUyHdBxDWt8SM09X7pfdbnMJccExszdR1Mx+aBXRVJs/+3fd2tC5ostDwplrH1CmQ==",
-			body:        body,
+			body:        testWebhookBody,
 			expected:    false,
 			expectedErr: false,
 		},
 		{
 			name:        "with corrupt public key",
 			publicKey:   "bad key",
-			signature:   validSig,
-			body:        body,
+			signature:   testWebhookSignature,
+			body:        testWebhookBody,
 			expected:    false,
 			expectedErr: true,
 		},
 		{
 			name:        "with incorrect public key type",
 			publicKey:   "-----BEGIN PUBLIC KEY-----\nMFwwDQYJKoZIhvcNAQEBBQADSwAwSAJBAL6xs9JmvgpdabCm8aXFCQH8KSGr/smD\n84Q7KTe0TFSX7rHRcS0XEWkLYgJapUNr7BYDeGTuoM/FYH98V17kL2MCAwEAAQ==\n-----END PUBLIC KEY-----",
-			signature:   validSig,
-			body:        body,
+			signature:   testWebhookSignature,
+			body:        testWebhookBody,
 			expected:    false,
 			expectedErr: true,
 		},
@@ -345,7 +352,7 @@ func TestValidateWebhookSignature(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actual, err := ValidateWebhookSignature(test.publicKey, test.signature, []byte(body))
+			actual, err := ValidateWebhookSignature(test.publicKey, test.signature, []byte(test.body))
 			gotError := err != nil
 			if test.expectedErr != gotError {
 				t.Fatalf("expected error %+v, actual %+v", test.expectedErr, gotError)
@@ -357,3 +364,54 @@ func TestValidateWebhookSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestWebhooksService_EnsureSubscriptions(t *testing.T) {
+	existing := strings.Join([]string{
+		`{ "_id": "hook_1", "type": "TRANSACTION", "state": "user_1" }`,
+		`{ "_id": "hook_2", "type": "TRANSACTION", "state": "user_1" }`,
+		`{ "_id": "hook_3", "type": "ACCOUNT", "state": "user_1" }`,
+	}, ",")
+
+	var calls []string
+	mockHttpClient := http.Client{Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		testTokenRequestHeaders(t, req, "app_token_123", "user_token_1")
+
+		var body string
+		switch req.Method {
+		case http.MethodGet:
+			body = fmt.Sprintf(collectionResponseJson, existing)
+		case http.MethodPost:
+			var subscribe WebhookSubscribeRequest
+			_ = json.NewDecoder(req.Body).Decode(&subscribe)
+			calls = append(calls, fmt.Sprintf("subscribe %s %s", subscribe.WebhookType, subscribe.State))
+			body = `{ "success": true, "item_id": "hook_4" }`
+		case http.MethodDelete:
+			calls = append(calls, "unsubscribe "+path.Base(req.URL.Path))
+			body = `{ "success": true }`
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	client := NewClient(&mockHttpClient, "app_token_123", "appSecret123", "")
+
+	desired := []WebhookSubscribeRequest{
+		{WebhookType: Transaction, State: "user_1"},
+		{WebhookType: Token, State: "user_1"},
+		{WebhookType: Token, State: "user_1"},
+	}
+	actual, res, err := client.Webhooks.EnsureSubscriptions(context.TODO(), "user_token_1", desired)
+	testClientAPIResponse(t, expectedSuccessAPIResponse, res, err)
+
+	expected := &SubscriptionChanges{
+		Subscribed: []WebhookResponse{{Id: "hook_4", Type: Token, State: "user_1"}},
+		Unsubscribed: []WebhookResponse{
+			{Id: "hook_2", Type: Transaction, State: "user_1"},
+			{Id: "hook_3", Type: Account, State: "user_1"},
+		},
+		Unchanged: []WebhookResponse{{Id: "hook_1", Type: Transaction, State: "user_1"}},
+	}
+	testClientResponse(t, expected, actual, nil)
+
+	// The new subscription is made before the old ones are removed.
+	testClientResponse(t, []string{"subscribe TOKEN user_1", "unsubscribe hook_2", "unsubscribe hook_3"}, calls, nil)
+}
@@ -0,0 +1,256 @@
+// Package batch runs an operation for many users at once, such as a nightly job that fetches every connected
+// user's accounts and transactions.
+//
+// Users are processed by a bounded pool of workers, and each user's operation is retried independently of the
+// others. Requests made by the operations share the rate limit of any akahu.RateLimitMiddleware on the client,
+// so a large batch doesn't exceed the limits of your Akahu app.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+const (
+	defaultWorkers     = 4
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = time.Second
+	defaultMaxDelay    = 30 * time.Second
+)
+
+// User is a user to run an operation for.
+type User struct {
+	ID          string
+	AccessToken string
+}
+
+// UsersFromTokenStore lists the users that have an access token in store.
+func UsersFromTokenStore(ctx context.Context, store akahu.TokenStore) ([]User, error) {
+	ids, err := store.UserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]User, 0, len(ids))
+	for _, id := range ids {
+		token, err := store.Get(ctx, id)
+		if errors.Is(err, akahu.ErrTokenNotFound) {
+			// Revoked since the IDs were listed.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, User{ID: id, AccessToken: token})
+	}
+
+	return users, nil
+}
+
+// Operation is run for each user, with a UserClient that makes calls with the user's access token.
+// Use CheckResponse to turn an unsuccessful response from Akahu into an error, so that it is retried.
+type Operation[T any] func(ctx context.Context, userID string, user *akahu.UserClient) (T, error)
+
+// Options configures Run.
+type Options struct {
+	// Workers is how many users are processed at once. Defaults to 4.
+	Workers int
+	// MaxAttempts is how many times a user's operation is attempted, including the first. Defaults to 3.
+	MaxAttempts int
+	// BaseDelay is the delay before a user's first retry, which doubles with each subsequent retry. Defaults to 1s.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries. Defaults to 30s.
+	MaxDelay time.Duration
+	// Retryable reports whether a failed operation should be retried. Defaults to IsRetryable.
+	Retryable func(err error) bool
+	// Checkpoint records the users that have been processed, so that a run that crashed or was cancelled can be
+	// resumed by running it again with the same Checkpoint. Optional.
+	Checkpoint Checkpoint
+}
+
+// Result is the outcome of the operation for a user.
+type Result[T any] struct {
+	UserID string
+	Value  T
+	Err    error
+	// Attempts is how many times the operation was run.
+	Attempts int
+	// Skipped is set if the user had already been processed according to the Checkpoint, in which case Value is
+	// the zero value.
+	Skipped bool
+}
+
+// Run runs op for each user, returning a Result for each in the order users were given.
+//
+// An error is only returned if the Checkpoint fails, or ctx is done before every user has been processed.
+// Failures of individual users are reported in their Result.
+func Run[T any](ctx context.Context, client *akahu.Client, users []User, op Operation[T], opts Options) ([]Result[T], error) {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = defaultBaseDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = defaultMaxDelay
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = IsRetryable
+	}
+
+	completed := map[string]bool{}
+	if opts.Checkpoint != nil {
+		var err error
+		if completed, err = opts.Checkpoint.Completed(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]Result[T], len(users))
+	jobs := make(chan int)
+
+	var (
+		wg            sync.WaitGroup
+		checkpointErr error
+		errOnce       sync.Once
+	)
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				user := users[i]
+				results[i] = runUser(ctx, client, user, op, opts)
+
+				if results[i].Err == nil && opts.Checkpoint != nil {
+					if err := opts.Checkpoint.MarkCompleted(ctx, user.ID); err != nil {
+						errOnce.Do(func() {
+							checkpointErr = err
+							cancel()
+						})
+					}
+				}
+			}
+		}()
+	}
+
+	for i, user := range users {
+		results[i].UserID = user.ID
+		results[i].Skipped = completed[user.ID]
+	}
+
+dispatch:
+	for i := range users {
+		if results[i].Skipped {
+			continue
+		}
+
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Users that weren't started before ctx was done failed with its error.
+	if err := ctx.Err(); err != nil {
+		for i := range results {
+			if !results[i].Skipped && results[i].Attempts == 0 {
+				results[i].Err = err
+			}
+		}
+	}
+
+	if checkpointErr != nil {
+		return results, checkpointErr
+	}
+
+	return results, ctx.Err()
+}
+
+func runUser[T any](ctx context.Context, client *akahu.Client, user User, op Operation[T], opts Options) Result[T] {
+	result := Result[T]{UserID: user.ID}
+	userClient := client.ForUser(user.AccessToken)
+
+	for {
+		result.Attempts++
+		result.Value, result.Err = op(ctx, user.ID, userClient)
+		if result.Err == nil || result.Attempts >= opts.MaxAttempts || ctx.Err() != nil || !opts.Retryable(result.Err) {
+			return result
+		}
+
+		delay := opts.BaseDelay << (result.Attempts - 1)
+		if delay > opts.MaxDelay || delay <= 0 {
+			delay = opts.MaxDelay
+		}
+		// Jitter stops users that failed together from retrying together.
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result
+		case <-timer.C:
+		}
+	}
+}
+
+// APIError is an unsuccessful response from Akahu, returned by CheckResponse.
+type APIError struct {
+	Response *akahu.APIResponse
+}
+
+func (e *APIError) Error() string {
+	if e.Response.Response == nil {
+		return fmt.Sprintf("batch: akahu request failed: %s", e.Response.Message)
+	}
+
+	return fmt.Sprintf("batch: akahu request failed with status %d: %s", e.Response.StatusCode, e.Response.Message)
+}
+
+// CheckResponse returns an *APIError if res is an unsuccessful response, otherwise nil.
+func CheckResponse(res *akahu.APIResponse) error {
+	if res == nil || res.Success {
+		return nil
+	}
+
+	return &APIError{Response: res}
+}
+
+// IsRetryable is the default Options.Retryable. Unsuccessful responses from Akahu are only retried if they were
+// rate limited or a server error, as other client errors such as a revoked token will fail again. Cancellation
+// is never retried, and other errors such as network failures always are.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Response.Response != nil {
+		status := apiErr.Response.StatusCode
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+
+	return true
+}
@@ -0,0 +1,201 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func testUsers(n int) []User {
+	users := make([]User, n)
+	for i := range users {
+		users[i] = User{ID: fmt.Sprintf("user_%d", i), AccessToken: fmt.Sprintf("user_token_%d", i)}
+	}
+
+	return users
+}
+
+func TestRun(t *testing.T) {
+	client := akahu.NewClient(nil, "app_token_123", "appSecret123", "")
+	users := testUsers(10)
+
+	var (
+		mu                sync.Mutex
+		attempts          = map[string]int{}
+		inFlight, maxSeen int
+	)
+	op := func(ctx context.Context, userID string, user *akahu.UserClient) (string, error) {
+		mu.Lock()
+		attempts[userID]++
+		attempt := attempts[userID]
+		inFlight++
+		if inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(time.Millisecond)
+
+		switch {
+		case userID == "user_1" && attempt < 3:
+			return "", CheckResponse(&akahu.APIResponse{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}})
+		case userID == "user_2":
+			return "", CheckResponse(&akahu.APIResponse{Message: "Unauthorized", Response: &http.Response{StatusCode: http.StatusUnauthorized}})
+		}
+
+		return "ok " + userID, nil
+	}
+
+	checkpoint := NewFileCheckpoint(filepath.Join(t.TempDir(), "checkpoint"))
+	_ = checkpoint.MarkCompleted(context.TODO(), "user_9")
+
+	results, err := Run(context.TODO(), client, users, op, Options{Workers: 3, BaseDelay: time.Millisecond, Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatalf("Run returned err %v", err)
+	}
+
+	if maxSeen > 3 {
+		t.Fatalf("expected at most 3 concurrent operations, actual %d", maxSeen)
+	}
+
+	for i, result := range results {
+		if result.UserID != users[i].ID {
+			t.Fatalf("expected result %d for %s, actual %s", i, users[i].ID, result.UserID)
+		}
+	}
+
+	if results[0].Value != "ok user_0" || results[0].Attempts != 1 {
+		t.Fatalf("expected user_0 to succeed first time, actual %+v", results[0])
+	}
+	if results[1].Err != nil || results[1].Attempts != 3 {
+		t.Fatalf("expected user_1 to succeed after retries, actual %+v", results[1])
+	}
+	var apiErr *APIError
+	if !errors.As(results[2].Err, &apiErr) || results[2].Attempts != 1 {
+		t.Fatalf("expected user_2 to fail without retries, actual %+v", results[2])
+	}
+	if !results[9].Skipped || attempts["user_9"] != 0 {
+		t.Fatalf("expected user_9 to be skipped, actual %+v", results[9])
+	}
+
+	// Only users that succeeded are checkpointed, so a second run only retries user_2.
+	completed, _ := checkpoint.Completed(context.TODO())
+	if len(completed) != 9 || completed["user_2"] {
+		t.Fatalf("expected every user but user_2 to be completed, actual %v", completed)
+	}
+
+	results, _ = Run(context.TODO(), client, users, op, Options{BaseDelay: time.Millisecond, Checkpoint: checkpoint})
+	var processed []string
+	for _, result := range results {
+		if !result.Skipped {
+			processed = append(processed, result.UserID)
+		}
+	}
+	if !reflect.DeepEqual(processed, []string{"user_2"}) {
+		t.Fatalf("expected only user_2 to be processed on resume, actual %v", processed)
+	}
+
+	_ = checkpoint.Remove()
+	if completed, _ := checkpoint.Completed(context.TODO()); len(completed) != 0 {
+		t.Fatalf("expected removed checkpoint to be empty, actual %v", completed)
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	client := akahu.NewClient(nil, "app_token_123", "appSecret123", "")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	op := func(ctx context.Context, userID string, user *akahu.UserClient) (int, error) {
+		cancel()
+		return 0, nil
+	}
+
+	results, err := Run(ctx, client, testUsers(5), op, Options{Workers: 1})
+	if err != context.Canceled {
+		t.Fatalf("expected err %v, actual %v", context.Canceled, err)
+	}
+	if results[4].Err != context.Canceled || results[4].Attempts != 0 {
+		t.Fatalf("expected last user not to be started, actual %+v", results[4])
+	}
+}
+
+func TestUsersFromTokenStore(t *testing.T) {
+	store := akahu.NewMemoryTokenStore()
+	_ = store.Put(context.TODO(), "user_2", "user_token_2")
+	_ = store.Put(context.TODO(), "user_1", "user_token_1")
+
+	users, err := UsersFromTokenStore(context.TODO(), store)
+	if err != nil {
+		t.Fatalf("UsersFromTokenStore returned err %v", err)
+	}
+
+	expected := []User{{ID: "user_1", AccessToken: "user_token_1"}, {ID: "user_2", AccessToken: "user_token_2"}}
+	if !reflect.DeepEqual(users, expected) {
+		t.Fatalf("expected %v, actual %v", expected, users)
+	}
+}
+
+func TestEnsureSubscriptions(t *testing.T) {
+	// user_0 is already subscribed, user_1 isn't.
+	var (
+		mu         sync.Mutex
+		subscribed []string
+	)
+	httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		body := `{ "success": true, "items": [] }`
+		switch {
+		case r.Method == http.MethodGet && token == "user_token_0":
+			body = `{ "success": true, "items": [{ "_id": "hook_0", "type": "TRANSACTION", "state": "user_0" }] }`
+		case r.Method == http.MethodPost:
+			var subscribe akahu.WebhookSubscribeRequest
+			_ = json.NewDecoder(r.Body).Decode(&subscribe)
+
+			mu.Lock()
+			subscribed = append(subscribed, fmt.Sprintf("%s %s %s", token, subscribe.WebhookType, subscribe.State))
+			mu.Unlock()
+			body = `{ "success": true, "item_id": "hook_1" }`
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	client := akahu.NewClient(httpClient, "app_token_123", "appSecret123", "")
+
+	desired := []akahu.WebhookSubscribeRequest{{WebhookType: akahu.Transaction}}
+	results, err := EnsureSubscriptions(context.TODO(), client, testUsers(2), desired, Options{})
+	if err != nil {
+		t.Fatalf("EnsureSubscriptions returned err %v", err)
+	}
+
+	if results[0].Err != nil || len(results[0].Value.Unchanged) != 1 || len(results[0].Value.Subscribed) != 0 {
+		t.Fatalf("expected user_0 to be unchanged, actual %+v", results[0])
+	}
+	if results[1].Err != nil || len(results[1].Value.Subscribed) != 1 {
+		t.Fatalf("expected user_1 to be subscribed, actual %+v", results[1])
+	}
+	if !reflect.DeepEqual(subscribed, []string{"user_token_1 TRANSACTION user_1"}) {
+		t.Fatalf("expected only user_1 to be subscribed with their ID as state, actual %v", subscribed)
+	}
+}
@@ -0,0 +1,122 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Checkpoint records which users a batch run has completed.
+type Checkpoint interface {
+	// Completed returns the IDs of the users that have been completed.
+	Completed(ctx context.Context) (map[string]bool, error)
+	MarkCompleted(ctx context.Context, userID string) error
+}
+
+// MemoryCheckpoint is a Checkpoint held in memory, which can resume a run that was cancelled within the same process.
+type MemoryCheckpoint struct {
+	mu        sync.Mutex
+	completed map[string]bool
+}
+
+// NewMemoryCheckpoint creates an empty MemoryCheckpoint.
+func NewMemoryCheckpoint() *MemoryCheckpoint {
+	return &MemoryCheckpoint{completed: map[string]bool{}}
+}
+
+func (c *MemoryCheckpoint) Completed(_ context.Context) (map[string]bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	completed := make(map[string]bool, len(c.completed))
+	for id := range c.completed {
+		completed[id] = true
+	}
+
+	return completed, nil
+}
+
+func (c *MemoryCheckpoint) MarkCompleted(_ context.Context, userID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.completed[userID] = true
+	return nil
+}
+
+// FileCheckpoint is a Checkpoint that appends the ID of each completed user to a file, so that a run can be resumed
+// after a crash. Each ID is synced to disk before MarkCompleted returns.
+//
+// Remove the file once a run has finished, so that the next run starts from the beginning.
+type FileCheckpoint struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileCheckpoint creates a FileCheckpoint that records completed users in the file at path.
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path: path}
+}
+
+func (c *FileCheckpoint) Completed(_ context.Context) (map[string]bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	completed := map[string]bool{}
+
+	f, err := os.Open(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return completed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// A crash while appending can leave a partial ID, which won't match any user.
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			completed[id] = true
+		}
+	}
+
+	return completed, scanner.Err()
+}
+
+func (c *FileCheckpoint) MarkCompleted(_ context.Context, userID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(userID + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Remove deletes the checkpoint file. It is not an error if the file doesn't exist.
+func (c *FileCheckpoint) Remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package batch
+
+import (
+	"context"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// EnsureSubscriptions runs WebhooksService.EnsureSubscriptions for every user, such as to roll out a new type of
+// webhook to all existing users. Desired webhooks with no State use each user's ID as their state, as
+// UserClient.Webhooks.Subscribe does. EnsureSubscriptions is idempotent, so users that fail are safe to retry.
+func EnsureSubscriptions(ctx context.Context, client *akahu.Client, users []User, desired []akahu.WebhookSubscribeRequest, opts Options) ([]Result[*akahu.SubscriptionChanges], error) {
+	return Run(ctx, client, users, func(ctx context.Context, userID string, user *akahu.UserClient) (*akahu.SubscriptionChanges, error) {
+		withState := make([]akahu.WebhookSubscribeRequest, len(desired))
+		for i, d := range desired {
+			if d.State == "" {
+				d.State = userID
+			}
+			withState[i] = d
+		}
+
+		changes, res, err := user.Webhooks.EnsureSubscriptions(ctx, withState)
+		if err != nil {
+			return changes, err
+		}
+
+		return changes, CheckResponse(res)
+	}, opts)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+var accountHeaders = []string{"ID", "NAME", "TYPE", "STATUS", "ACCOUNT", "AVAILABLE", "CURRENT", "CURRENCY"}
+
+func accountRow(account akahu.AccountResponse) []string {
+	return []string{
+		string(account.ID),
+		account.Name,
+		account.Type,
+		account.Status,
+		account.FormattedAccount,
+		account.Balance.Available.String(),
+		account.Balance.Current.String(),
+		account.Balance.Currency,
+	}
+}
+
+func accountsList(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(flag.NewFlagSet("accounts list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	accounts, res, err := a.client.Accounts.List(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(accounts))
+	for i, account := range accounts {
+		rows[i] = accountRow(account)
+	}
+
+	return a.print(accounts, accountHeaders, rows)
+}
+
+func accountsGet(ctx context.Context, a *app, args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("accounts get", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	id := akahu.AccountID(args[0])
+	if err := id.Validate(); err != nil {
+		return err
+	}
+
+	account, res, err := a.client.Accounts.Get(ctx, token, id)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	return a.print(account, accountHeaders, [][]string{accountRow(*account)})
+}
+
+func accountsRevoke(ctx context.Context, a *app, args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("accounts revoke", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	id := akahu.AccountID(args[0])
+	if err := id.Validate(); err != nil {
+		return err
+	}
+
+	_, res, err := a.client.Accounts.Revoke(ctx, token, id)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Revoked access to account %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+func authURL(_ context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("auth url", flag.ContinueOnError)
+	email := flags.String("email", "", "prefill the user's email address")
+	connection := flags.String("connection", "", "take the user straight to this connection")
+	scopes := flags.String("scope", "", "comma separated scopes, defaults to ENDURING_CONSENT")
+	state := flags.String("state", "", "state returned to the redirect URI")
+
+	if _, err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+
+	var options akahu.AuthorizationURLOptions
+	if *email != "" {
+		options.Email = email
+	}
+	if *connection != "" {
+		options.Connection = connection
+	}
+	if *state != "" {
+		options.State = state
+	}
+	if *scopes != "" {
+		for _, scope := range strings.Split(*scopes, ",") {
+			options.Scopes = append(options.Scopes, akahu.Scope(strings.TrimSpace(scope)))
+		}
+	}
+
+	authURL, err := a.client.Auth.BuildAuthorizationURL(options)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.out, authURL)
+	return nil
+}
+
+func authExchange(ctx context.Context, a *app, args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("auth exchange", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	exchange, res, err := a.client.Auth.Exchange(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	return a.print(exchange, []string{"ACCESS TOKEN", "TYPE", "SCOPE"}, [][]string{{exchange.AccessToken, exchange.TokenType, exchange.Scope}})
+}
+
+func authRevoke(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(flag.NewFlagSet("auth revoke", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	_, res, err := a.client.Auth.RevokeToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.out, "Revoked user access token")
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+const defaultRedirectURI = "https://example.com/auth/akahu"
+
+type config struct {
+	AppToken    string `json:"app_token"`
+	AppSecret   string `json:"app_secret"`
+	UserToken   string `json:"user_token"`
+	RedirectURI string `json:"redirect_uri"`
+}
+
+type app struct {
+	client *akahu.Client
+	config config
+	json   bool
+	out    io.Writer
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "akahu", "config.json")
+}
+
+// loadConfig reads the config file, if it exists, then overrides it with any credentials set in the environment.
+func loadConfig(path string) (config, error) {
+	var c config
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return c, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &c); err != nil {
+				return c, err
+			}
+		}
+	}
+
+	for env, field := range map[string]*string{
+		"AKAHU_APP_TOKEN":    &c.AppToken,
+		"AKAHU_APP_SECRET":   &c.AppSecret,
+		"AKAHU_USER_TOKEN":   &c.UserToken,
+		"AKAHU_REDIRECT_URI": &c.RedirectURI,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+
+	if c.RedirectURI == "" {
+		c.RedirectURI = defaultRedirectURI
+	}
+	if c.AppToken == "" {
+		return c, errors.New("no app token, set AKAHU_APP_TOKEN or app_token in the config file")
+	}
+
+	return c, nil
+}
+
+func newApp(c config, jsonOutput bool) *app {
+	return &app{
+		client: akahu.NewClient(nil, c.AppToken, c.AppSecret, c.RedirectURI),
+		config: c,
+		json:   jsonOutput,
+		out:    os.Stdout,
+	}
+}
+
+func (a *app) userToken() (string, error) {
+	if a.config.UserToken == "" {
+		return "", errors.New("no user token, set AKAHU_USER_TOKEN or user_token in the config file")
+	}
+
+	return a.config.UserToken, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+func connectionsList(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(flag.NewFlagSet("connections list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	connections, res, err := a.client.Connections.List(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(connections))
+	for i, connection := range connections {
+		rows[i] = []string{string(connection.Id), connection.Name, stringOrEmpty(connection.Url)}
+	}
+
+	return a.print(connections, []string{"ID", "NAME", "URL"}, rows)
+}
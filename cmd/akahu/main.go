@@ -0,0 +1,150 @@
+// Command akahu is a command line interface to the Akahu API.
+//
+// Usage:
+//
+//	akahu [-config path] [-json] <command> <subcommand> [flags] [args]
+//
+// Commands:
+//
+//	accounts list
+//	accounts get <account id>
+//	accounts revoke <account id>
+//	transactions list [-start date] [-end date] [-pending] [-account account id]
+//	connections list
+//	me
+//	webhooks list
+//	webhooks subscribe -type type [-state state]
+//	webhooks unsubscribe <webhook id>
+//	webhooks events -status status [-start date] [-end date]
+//	auth url [-email email] [-connection connection id] [-scope scopes] [-state state]
+//	auth exchange <code>
+//	auth revoke
+//
+// Dates are either YYYY-MM-DD, in local time, or RFC 3339 timestamps. An -end date includes the whole of that day.
+//
+// Credentials are read from the AKAHU_APP_TOKEN, AKAHU_APP_SECRET, AKAHU_USER_TOKEN and AKAHU_REDIRECT_URI
+// environment variables, falling back to the config file (by default akahu/config.json in the user's config directory):
+//
+//	{
+//	  "app_token": "app_token_...",
+//	  "app_secret": "...",
+//	  "user_token": "user_token_...",
+//	  "redirect_uri": "https://example.com/auth/akahu"
+//	}
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+type command struct {
+	run   func(ctx context.Context, app *app, args []string) error
+	usage string
+}
+
+var commands = map[string]map[string]command{
+	"accounts": {
+		"list":   {run: accountsList, usage: "accounts list"},
+		"get":    {run: accountsGet, usage: "accounts get <account id>"},
+		"revoke": {run: accountsRevoke, usage: "accounts revoke <account id>"},
+	},
+	"transactions": {
+		"list": {run: transactionsList, usage: "transactions list [-start date] [-end date] [-pending] [-account account id]"},
+	},
+	"connections": {
+		"list": {run: connectionsList, usage: "connections list"},
+	},
+	"me": {
+		"": {run: me, usage: "me"},
+	},
+	"webhooks": {
+		"list":        {run: webhooksList, usage: "webhooks list"},
+		"subscribe":   {run: webhooksSubscribe, usage: "webhooks subscribe -type type [-state state]"},
+		"unsubscribe": {run: webhooksUnsubscribe, usage: "webhooks unsubscribe <webhook id>"},
+		"events":      {run: webhooksEvents, usage: "webhooks events -status status [-start date] [-end date]"},
+	},
+	"auth": {
+		"url":      {run: authURL, usage: "auth url [-email email] [-connection connection id] [-scope scopes] [-state state]"},
+		"exchange": {run: authExchange, usage: "auth exchange <code>"},
+		"revoke":   {run: authRevoke, usage: "auth revoke"},
+	},
+}
+
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "akahu: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("akahu", flag.ContinueOnError)
+	configPath := flags.String("config", defaultConfigPath(), "path to the config file")
+	jsonOutput := flags.Bool("json", false, "output JSON rather than a table")
+	flags.Usage = usage(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		flags.Usage()
+		return errUsage
+	}
+
+	cmd, ok := subcommands[""]
+	args = args[1:]
+	if !ok {
+		if len(args) == 0 {
+			flags.Usage()
+			return errUsage
+		}
+
+		cmd, ok = subcommands[args[0]]
+		if !ok {
+			flags.Usage()
+			return errUsage
+		}
+		args = args[1:]
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	return cmd.run(ctx, newApp(config, *jsonOutput), args)
+}
+
+func usage(flags *flag.FlagSet) func() {
+	return func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: akahu [-config path] [-json] <command> [flags] [args]\n\nCommands:\n")
+		for _, name := range []string{"accounts", "transactions", "connections", "me", "webhooks", "auth"} {
+			for _, sub := range sortedSubcommands(commands[name]) {
+				fmt.Fprintf(out, "  %s\n", commands[name][sub].usage)
+			}
+		}
+		fmt.Fprintf(out, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+}
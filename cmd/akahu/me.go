@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+func me(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(flag.NewFlagSet("me", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	user, res, err := a.client.Me.Get(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	var createdAt string
+	if user.CreatedAt != nil {
+		createdAt = formatTime(*user.CreatedAt)
+	}
+
+	return a.print(user, []string{"ID", "EMAIL", "NAME", "PREFERRED NAME", "MOBILE", "CREATED"}, [][]string{{
+		string(user.Id),
+		user.Email,
+		stringOrEmpty(user.FirstName) + " " + stringOrEmpty(user.LastName),
+		stringOrEmpty(user.PreferredName),
+		stringOrEmpty(user.Mobile),
+		createdAt,
+	}})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// print writes v as JSON, or as a table of the given headers and rows.
+func (a *app) print(v interface{}, headers []string, rows [][]string) error {
+	if a.json {
+		enc := json.NewEncoder(a.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func checkResponse(res *akahu.APIResponse) error {
+	if res.Success {
+		return nil
+	}
+
+	if res.Message == "" {
+		return fmt.Errorf("request failed with status %d", res.StatusCode)
+	}
+
+	return fmt.Errorf("request failed with status %d: %s", res.StatusCode, res.Message)
+}
+
+// parseFlags parses the flags of a subcommand, returning the remaining arguments,
+// which must number exactly nArgs.
+func parseFlags(flags *flag.FlagSet, args []string, nArgs int) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+
+	if flags.NArg() != nArgs {
+		return nil, fmt.Errorf("expected %d argument(s), got %d", nArgs, flags.NArg())
+	}
+
+	return flags.Args(), nil
+}
+
+// dateFlag is a flag.Value that accepts either a date (2006-01-02), which is the start of that day in local time,
+// or an RFC 3339 timestamp.
+type dateFlag struct {
+	time     time.Time
+	dateOnly bool
+}
+
+func (d *dateFlag) String() string {
+	if d.time.IsZero() {
+		return ""
+	}
+
+	return d.time.Format(time.RFC3339)
+}
+
+func (d *dateFlag) Set(value string) error {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		d.time, d.dateOnly = t, true
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+
+	d.time, d.dateOnly = t, false
+	return nil
+}
+
+// dateRange registers -start and -end flags, returning a function that resolves them once parsed.
+// The range defaults to the last 30 days. An end date includes the whole of that day.
+func dateRange(flags *flag.FlagSet) func() (time.Time, time.Time) {
+	var start, end dateFlag
+	flags.Var(&start, "start", "start of the date range, defaults to 30 days before the end")
+	flags.Var(&end, "end", "end of the date range, inclusive, defaults to now")
+
+	return func() (time.Time, time.Time) {
+		endTime := end.time
+		if endTime.IsZero() {
+			endTime = time.Now()
+		} else if end.dateOnly {
+			endTime = endTime.AddDate(0, 0, 1)
+		}
+
+		startTime := start.time
+		if startTime.IsZero() {
+			startTime = endTime.AddDate(0, 0, -30)
+		}
+
+		return startTime, endTime
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func sortedSubcommands(subcommands map[string]command) []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestDateFlag_Set(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("NZDT", 13*60*60)
+	defer func() { time.Local = local }()
+
+	tests := []struct {
+		name             string
+		value            string
+		expectedTime     time.Time
+		expectedDateOnly bool
+		expectedErr      bool
+	}{
+		{
+			name:             "with date",
+			value:            "2024-01-02",
+			expectedTime:     time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC),
+			expectedDateOnly: true,
+		},
+		{
+			name:         "with timestamp",
+			value:        "2024-01-02T03:04:05Z",
+			expectedTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:        "with invalid date",
+			value:       "02/01/2024",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var d dateFlag
+			err := d.Set(test.value)
+			if (err != nil) != test.expectedErr {
+				t.Fatalf("expected error %v, actual %v", test.expectedErr, err)
+			}
+			if !d.time.Equal(test.expectedTime) || d.dateOnly != test.expectedDateOnly {
+				t.Fatalf("expected %v (date only %v), actual %v (date only %v)", test.expectedTime, test.expectedDateOnly, d.time, d.dateOnly)
+			}
+		})
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("NZDT", 13*60*60)
+	defer func() { time.Local = local }()
+
+	tests := []struct {
+		name          string
+		args          []string
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{
+			name:          "with dates",
+			args:          []string{"-start", "2024-01-01", "-end", "2024-01-31"},
+			expectedStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+			expectedEnd:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:          "with end timestamp",
+			args:          []string{"-end", "2024-01-31T12:00:00Z"},
+			expectedStart: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			dates := dateRange(flags)
+			if _, err := parseFlags(flags, test.args, 0); err != nil {
+				t.Fatalf("parseFlags returned err %v", err)
+			}
+
+			start, end := dates()
+			if !start.Equal(test.expectedStart) || !end.Equal(test.expectedEnd) {
+				t.Fatalf("expected %v to %v, actual %v to %v", test.expectedStart, test.expectedEnd, start, end)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(&bytes.Buffer{})
+	pending := flags.Bool("pending", false, "")
+
+	args, err := parseFlags(flags, []string{"-pending", "acc_1"}, 1)
+	if err != nil || !*pending || len(args) != 1 || args[0] != "acc_1" {
+		t.Fatalf("expected pending and argument acc_1, actual %v %v %v", *pending, args, err)
+	}
+
+	if _, err := parseFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{"acc_1"}, 0); err == nil {
+		t.Fatalf("expected error for unexpected argument")
+	}
+
+	unknown := flag.NewFlagSet("test", flag.ContinueOnError)
+	unknown.SetOutput(&bytes.Buffer{})
+	if _, err := parseFlags(unknown, []string{"-unknown"}, 0); err != errUsage {
+		t.Fatalf("expected errUsage for unknown flag, actual %v", err)
+	}
+}
+
+func TestApp_Print(t *testing.T) {
+	v := []map[string]string{{"id": "acc_1", "name": "Everyday"}}
+	headers := []string{"ID", "NAME"}
+	rows := [][]string{{"acc_1", "Everyday"}, {"acc_22", "Savings"}}
+
+	tests := []struct {
+		name     string
+		json     bool
+		expected string
+	}{
+		{
+			name:     "with table",
+			expected: "ID      NAME\nacc_1   Everyday\nacc_22  Savings\n",
+		},
+		{
+			name:     "with JSON",
+			json:     true,
+			expected: "[\n  {\n    \"id\": \"acc_1\",\n    \"name\": \"Everyday\"\n  }\n]\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			a := &app{json: test.json, out: &out}
+
+			if err := a.print(v, headers, rows); err != nil {
+				t.Fatalf("print returned err %v", err)
+			}
+			if out.String() != test.expected {
+				t.Fatalf("expected %q, actual %q", test.expected, out.String())
+			}
+		})
+	}
+}
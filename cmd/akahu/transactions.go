@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+func transactionsList(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("transactions list", flag.ContinueOnError)
+	dates := dateRange(flags)
+	pending := flags.Bool("pending", false, "list pending rather than settled transactions")
+	accountID := flags.String("account", "", "only list transactions for this account")
+
+	if _, err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+
+	account := akahu.AccountID(*accountID)
+	if account != "" {
+		if err := account.Validate(); err != nil {
+			return err
+		}
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	startTime, endTime := dates()
+
+	var list func(ctx context.Context, userAccessToken string, startTime, endTime time.Time) ([]akahu.TransactionResponse, *akahu.APIResponse, error)
+	switch {
+	case account != "" && *pending:
+		list = byAccount(a.client.Transactions.ListPendingByAccount, account)
+	case account != "":
+		list = byAccount(a.client.Transactions.ListByAccount, account)
+	case *pending:
+		list = a.client.Transactions.ListPending
+	default:
+		list = a.client.Transactions.List
+	}
+
+	transactions, res, err := list(ctx, token, startTime, endTime)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(transactions))
+	for i, transaction := range transactions {
+		var merchant, category string
+		if transaction.Merchant != nil {
+			merchant = transaction.Merchant.Name
+		}
+		if transaction.Category != nil {
+			category = transaction.Category.Name
+		}
+
+		rows[i] = []string{
+			string(transaction.Id),
+			transaction.Date.Local().Format(time.DateOnly),
+			string(transaction.Account),
+			transaction.Amount.String(),
+			transaction.Type,
+			transaction.Description,
+			merchant,
+			category,
+		}
+	}
+
+	return a.print(transactions, []string{"ID", "DATE", "ACCOUNT", "AMOUNT", "TYPE", "DESCRIPTION", "MERCHANT", "CATEGORY"}, rows)
+}
+
+func byAccount(
+	list func(ctx context.Context, userAccessToken string, accountID akahu.AccountID, startTime, endTime time.Time) ([]akahu.TransactionResponse, *akahu.APIResponse, error),
+	accountID akahu.AccountID,
+) func(ctx context.Context, userAccessToken string, startTime, endTime time.Time) ([]akahu.TransactionResponse, *akahu.APIResponse, error) {
+	return func(ctx context.Context, userAccessToken string, startTime, endTime time.Time) ([]akahu.TransactionResponse, *akahu.APIResponse, error) {
+		return list(ctx, userAccessToken, accountID, startTime, endTime)
+	}
+}
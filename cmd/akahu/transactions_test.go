@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestTransactionsList(t *testing.T) {
+	var queries []string
+	httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		queries = append(queries, r.URL.RawQuery)
+
+		body := `{ "success": true, "items": [{ "_id": "trans_1", "_account": "acc_1", "date": "2024-01-01T00:00:00Z", "amount": -10.5, "type": "EFTPOS", "description": "Coffee" }], "cursor": { "next": "page_1" } }`
+		if r.URL.Query().Get("cursor") == "page_1" {
+			body = `{ "success": true, "items": [{ "_id": "trans_2", "_account": "acc_1", "date": "2024-01-02T00:00:00Z", "amount": 100, "type": "DIRECT CREDIT", "description": "Pay" }], "cursor": { "next": null } }`
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+
+	var out bytes.Buffer
+	a := &app{
+		client: akahu.NewClient(httpClient, "app_token_123", "appSecret123", ""),
+		config: config{UserToken: "user_token_1"},
+		out:    &out,
+	}
+
+	if err := transactionsList(context.TODO(), a, []string{"-start", "2024-01-01T00:00:00Z", "-end", "2024-01-31T00:00:00Z"}); err != nil {
+		t.Fatalf("transactionsList returned err %v", err)
+	}
+
+	if len(queries) != 2 {
+		t.Fatalf("expected both pages to be fetched, actual queries %v", queries)
+	}
+	for _, id := range []string{"trans_1", "trans_2"} {
+		if !strings.Contains(out.String(), id) {
+			t.Fatalf("expected %s in output, actual %s", id, out.String())
+		}
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 3 {
+		t.Fatalf("expected a header and 2 rows, actual %d lines: %s", lines, out.String())
+	}
+}
+
+func TestTransactionsList_Unsuccessful(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(`{ "success": false, "message": "Unauthorized" }`))}, nil
+	})}
+
+	a := &app{
+		client: akahu.NewClient(httpClient, "app_token_123", "appSecret123", ""),
+		config: config{UserToken: "user_token_1"},
+		out:    &bytes.Buffer{},
+	}
+
+	err := transactionsList(context.TODO(), a, nil)
+	if expected := fmt.Sprintf("request failed with status %d: Unauthorized", http.StatusUnauthorized); err == nil || err.Error() != expected {
+		t.Fatalf("expected error %q, actual %v", expected, err)
+	}
+}
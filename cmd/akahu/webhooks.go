@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+func webhooksList(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(flag.NewFlagSet("webhooks list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	webhooks, res, err := a.client.Webhooks.List(ctx, token)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(webhooks))
+	for i, webhook := range webhooks {
+		rows[i] = []string{string(webhook.Id), webhook.State, webhook.Url, formatTime(webhook.CreatedAt), formatTime(webhook.LastCalledAt)}
+	}
+
+	return a.print(webhooks, []string{"ID", "STATE", "URL", "CREATED", "LAST CALLED"}, rows)
+}
+
+func webhooksSubscribe(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("webhooks subscribe", flag.ContinueOnError)
+	webhookType := flags.String("type", "", "type of webhook, e.g. TRANSACTION")
+	state := flags.String("state", "", "state included in each webhook payload")
+
+	if _, err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	if *webhookType == "" {
+		return errors.New("-type is required")
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	id, res, err := a.client.Webhooks.Subscribe(ctx, token, akahu.WebhookSubscribeRequest{
+		WebhookType: akahu.WebhookType(*webhookType),
+		State:       *state,
+	})
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	var webhookID string
+	if id != nil {
+		webhookID = string(*id)
+	}
+
+	return a.print(map[string]string{"id": webhookID}, []string{"ID"}, [][]string{{webhookID}})
+}
+
+func webhooksUnsubscribe(ctx context.Context, a *app, args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("webhooks unsubscribe", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	id := akahu.WebhookID(args[0])
+	if err := id.Validate(); err != nil {
+		return err
+	}
+
+	_, res, err := a.client.Webhooks.Unsubscribe(ctx, token, id)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Unsubscribed webhook %s\n", args[0])
+	return nil
+}
+
+func webhooksEvents(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("webhooks events", flag.ContinueOnError)
+	dates := dateRange(flags)
+	status := flags.String("status", "", "status of the events, one of SENT, FAILED or RETRY")
+
+	if _, err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	if *status == "" {
+		return errors.New("-status is required")
+	}
+
+	token, err := a.userToken()
+	if err != nil {
+		return err
+	}
+
+	startTime, endTime := dates()
+	events, res, err := a.client.Webhooks.ListEvents(ctx, token, *status, startTime, endTime)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(res); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(events))
+	for i, event := range events {
+		rows[i] = []string{
+			event.Id,
+			string(event.Hook),
+			string(event.Status),
+			string(event.Payload.WebhookType),
+			event.Payload.WebhookCode,
+			formatTime(event.CreatedAt),
+			formatTime(event.LastFailedAt),
+		}
+	}
+
+	return a.print(events, []string{"ID", "HOOK", "STATUS", "TYPE", "CODE", "CREATED", "LAST FAILED"}, rows)
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"github.com/shopspring/decimal"
+)
+
+// Column is a column of a CSV export.
+type Column struct {
+	Header string
+	Value  func(t akahu.TransactionResponse) string
+}
+
+// DateColumn creates a column of the transaction date, formatted with layout in loc (NZ if nil).
+func DateColumn(header, layout string, loc *time.Location) Column {
+	loc = location(loc)
+	return Column{
+		Header: header,
+		Value: func(t akahu.TransactionResponse) string {
+			return t.Date.In(loc).Format(layout)
+		},
+	}
+}
+
+var (
+	ColumnID          = Column{"ID", func(t akahu.TransactionResponse) string { return string(t.Id) }}
+	ColumnAccount     = Column{"Account", func(t akahu.TransactionResponse) string { return string(t.Account) }}
+	ColumnDate        = DateColumn("Date", time.DateOnly, nil)
+	ColumnDescription = Column{"Description", func(t akahu.TransactionResponse) string { return t.Description }}
+	ColumnAmount      = Column{"Amount", func(t akahu.TransactionResponse) string { return t.Amount.String() }}
+	ColumnBalance     = Column{"Balance", func(t akahu.TransactionResponse) string { return t.Balance.String() }}
+	ColumnType        = Column{"Type", func(t akahu.TransactionResponse) string { return t.Type }}
+	ColumnPayee       = Column{"Payee", payee}
+	ColumnMerchant    = Column{"Merchant", merchantName}
+	ColumnCategory    = Column{"Category", categoryName}
+	ColumnParticulars = Column{"Particulars", particulars}
+	ColumnCode        = Column{"Code", code}
+	ColumnReference   = Column{"Reference", reference}
+	ColumnOtherParty  = Column{"Other Account", otherAccount}
+)
+
+// DefaultColumns are the columns written by WriteCSV if none are given.
+var DefaultColumns = []Column{
+	ColumnID,
+	ColumnAccount,
+	ColumnDate,
+	ColumnDescription,
+	ColumnAmount,
+	ColumnBalance,
+	ColumnType,
+	ColumnMerchant,
+	ColumnCategory,
+	ColumnParticulars,
+	ColumnCode,
+	ColumnReference,
+	ColumnOtherParty,
+}
+
+// WriteCSV writes a header row followed by a row per transaction, with the given columns (DefaultColumns if empty).
+//
+// Descriptions, references and other text can be set by whoever made the payment, so cells that a spreadsheet would
+// run as a formula, starting with =, +, -, @, tab or carriage return, are prefixed with a single quote. Numbers, such
+// as negative amounts, are written as they are.
+func WriteCSV(w io.Writer, it Iterator, columns []Column) error {
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+
+	cw := csv.NewWriter(w)
+
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.Header
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	for it.Next() {
+		t := it.Transaction()
+		for i, column := range columns {
+			record[i] = csvCell(column.Value(t))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell neutralises a value that a spreadsheet would treat as a formula, unless it is a number.
+func csvCell(value string) string {
+	if value == "" || !strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return value
+	}
+	if _, err := decimal.NewFromString(value); err == nil {
+		return value
+	}
+
+	return "'" + value
+}
@@ -0,0 +1,151 @@
+// Package export writes Akahu transactions to the file formats used by accounting software: CSV, Xero bank statement CSV, OFX and QIF.
+//
+// Amounts and balances are written from their decimal.Decimal values, so no precision is lost.
+// Akahu returns dates in UTC, they are written in New Zealand time by default so that each transaction
+// appears on the date it was made.
+package export
+
+import (
+	"strings"
+	"time"
+	_ "time/tzdata" // Transactions are dated in New Zealand time, even where the system has no zoneinfo database.
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// NZ is the Pacific/Auckland time zone, the default location dates are written in.
+var NZ = mustLoadLocation("Pacific/Auckland")
+
+// Iterator yields transactions one at a time, so that transactions can be exported while they are being fetched.
+type Iterator interface {
+	// Next advances to the next transaction, returning false when there are none left or an error occurred.
+	Next() bool
+	Transaction() akahu.TransactionResponse
+	Err() error
+}
+
+type sliceIterator struct {
+	transactions []akahu.TransactionResponse
+	i            int
+}
+
+// Slice returns an Iterator over a slice of transactions.
+func Slice(transactions []akahu.TransactionResponse) Iterator {
+	return &sliceIterator{transactions: transactions, i: -1}
+}
+
+func (it *sliceIterator) Next() bool {
+	it.i++
+	return it.i < len(it.transactions)
+}
+
+func (it *sliceIterator) Transaction() akahu.TransactionResponse {
+	return it.transactions[it.i]
+}
+
+func (it *sliceIterator) Err() error {
+	return nil
+}
+
+func collect(it Iterator) ([]akahu.TransactionResponse, error) {
+	var transactions []akahu.TransactionResponse
+	for it.Next() {
+		transactions = append(transactions, it.Transaction())
+	}
+
+	return transactions, it.Err()
+}
+
+func location(loc *time.Location) *time.Location {
+	if loc == nil {
+		return NZ
+	}
+
+	return loc
+}
+
+// payee is the merchant name if Akahu has identified the merchant, otherwise the description.
+func payee(t akahu.TransactionResponse) string {
+	if t.Merchant != nil && t.Merchant.Name != "" {
+		return t.Merchant.Name
+	}
+
+	return t.Description
+}
+
+func merchantName(t akahu.TransactionResponse) string {
+	if t.Merchant == nil {
+		return ""
+	}
+
+	return t.Merchant.Name
+}
+
+func categoryName(t akahu.TransactionResponse) string {
+	if t.Category == nil {
+		return ""
+	}
+
+	return t.Category.Name
+}
+
+func particulars(t akahu.TransactionResponse) string {
+	if t.Meta == nil {
+		return ""
+	}
+
+	return stringOrEmpty(t.Meta.Particulars)
+}
+
+func code(t akahu.TransactionResponse) string {
+	if t.Meta == nil {
+		return ""
+	}
+
+	return stringOrEmpty(t.Meta.Code)
+}
+
+func reference(t akahu.TransactionResponse) string {
+	if t.Meta == nil {
+		return ""
+	}
+
+	return stringOrEmpty(t.Meta.Reference)
+}
+
+func otherAccount(t akahu.TransactionResponse) string {
+	if t.Meta == nil {
+		return ""
+	}
+
+	return stringOrEmpty(t.Meta.OtherAccount)
+}
+
+// memo joins the particulars, code and reference, as they appear on a NZ bank statement.
+func memo(t akahu.TransactionResponse) string {
+	var parts []string
+	for _, part := range []string{particulars(t), code(t), reference(t)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return loc
+}
@@ -0,0 +1,237 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"github.com/shopspring/decimal"
+)
+
+func testTransactions() []akahu.TransactionResponse {
+	particulars, code, reference, otherAccount := "Lunch", "Cafe", "Ref123", "12-3456-7890123-00"
+
+	return []akahu.TransactionResponse{
+		{
+			Id:          "trans_1",
+			Account:     "acc_1",
+			Date:        time.Date(2023, 3, 31, 11, 30, 0, 0, time.UTC), // 1 April 00:30 in NZ.
+			Description: "CAFE CENTRAL WELLINGTON",
+			Amount:      decimal.RequireFromString("-12.345"),
+			Balance:     decimal.RequireFromString("1000.10"),
+			Type:        "EFTPOS",
+			Merchant:    &akahu.Merchant{Name: "Cafe Central"},
+			Category:    &akahu.Category{Name: "Cafes and restaurants"},
+			Meta: &akahu.Meta{
+				Particulars:  &particulars,
+				Code:         &code,
+				Reference:    &reference,
+				OtherAccount: &otherAccount,
+			},
+		},
+		{
+			Id:          "trans_2",
+			Account:     "acc_1",
+			Date:        time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC),
+			Description: "SALARY",
+			Amount:      decimal.RequireFromString("2500.00"),
+			Balance:     decimal.RequireFromString("3487.755"),
+			Type:        "CREDIT",
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  []Column
+		expected string
+	}{
+		{
+			name: "with default columns",
+			expected: "ID,Account,Date,Description,Amount,Balance,Type,Merchant,Category,Particulars,Code,Reference,Other Account\n" +
+				"trans_1,acc_1,2023-04-01,CAFE CENTRAL WELLINGTON,-12.345,1000.1,EFTPOS,Cafe Central,Cafes and restaurants,Lunch,Cafe,Ref123,12-3456-7890123-00\n" +
+				"trans_2,acc_1,2023-04-02,SALARY,2500,3487.755,CREDIT,,,,,,\n",
+		},
+		{
+			name:    "with custom columns",
+			columns: []Column{DateColumn("Posted", "02/01/2006", time.UTC), ColumnPayee, ColumnAmount},
+			expected: "Posted,Payee,Amount\n" +
+				"31/03/2023,Cafe Central,-12.345\n" +
+				"02/04/2023,SALARY,2500\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteCSV(&buf, Slice(testTransactions()), test.columns); err != nil {
+				t.Fatalf("WriteCSV returned err %v", err)
+			}
+
+			if actual := buf.String(); actual != test.expected {
+				t.Fatalf("expected\n%s\nactual\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriteOFX(t *testing.T) {
+	var buf bytes.Buffer
+	opts := OFXOptions{
+		Accounts: map[akahu.AccountID]OFXAccount{
+			"acc_1": OFXAccountFrom(akahu.AccountResponse{ID: "acc_1", FormattedAccount: "12-3456-7890123-00", Type: "SAVINGS"}),
+		},
+	}
+	if err := WriteOFX(&buf, Slice(testTransactions()), opts); err != nil {
+		t.Fatalf("WriteOFX returned err %v", err)
+	}
+	actual := buf.String()
+
+	for _, expected := range []string{
+		"<BANKID>123456</BANKID>",
+		"<ACCTID>789012300</ACCTID>",
+		"<ACCTTYPE>SAVINGS</ACCTTYPE>",
+		"<CURDEF>NZD</CURDEF>",
+		"<DTSTART>20230401003000.000[+13:NZDT]</DTSTART>",
+		"<TRNTYPE>POS</TRNTYPE>",
+		"<DTPOSTED>20230401003000.000[+13:NZDT]</DTPOSTED>",
+		"<TRNAMT>-12.345</TRNAMT>",
+		"<FITID>trans_1</FITID>",
+		"<NAME>Cafe Central</NAME>",
+		"<MEMO>CAFE CENTRAL WELLINGTON Lunch Cafe Ref123</MEMO>",
+		"<TRNTYPE>CREDIT</TRNTYPE>",
+		"<BALAMT>3487.755</BALAMT>",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected OFX to contain %s, actual\n%s", expected, actual)
+		}
+	}
+}
+
+func TestFormatOFXDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected string
+	}{
+		{
+			name:     "with whole hours",
+			time:     time.Date(2023, 4, 1, 0, 30, 0, 0, time.FixedZone("NZDT", 13*3600)),
+			expected: "20230401003000.000[+13:NZDT]",
+		},
+		{
+			name:     "with fractional hours",
+			time:     time.Date(2023, 4, 1, 6, 15, 0, 0, time.FixedZone("NPT", 5*3600+45*60)),
+			expected: "20230401061500.000[+5.75:NPT]",
+		},
+		{
+			name:     "with negative offset",
+			time:     time.Date(2023, 4, 1, 9, 0, 0, 0, time.FixedZone("NST", -(3*3600+30*60))),
+			expected: "20230401090000.000[-3.5:NST]",
+		},
+		{
+			name:     "with UTC",
+			time:     time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC),
+			expected: "20230401120000.000[+0:UTC]",
+		},
+		{
+			name:     "with numeric zone name",
+			time:     time.Date(2023, 4, 1, 6, 15, 0, 0, time.FixedZone("+0545", 5*3600+45*60)),
+			expected: "20230401061500.000[+5.75]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := formatOFXDate(test.time); actual != test.expected {
+				t.Fatalf("expected %s, actual %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriteOFX_LedgerBalance(t *testing.T) {
+	day := time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC)
+	transactions := []akahu.TransactionResponse{
+		{Id: "trans_2", Account: "acc_1", Date: day, CreatedAt: day.Add(2 * time.Hour), Amount: decimal.NewFromInt(5), Balance: decimal.NewFromInt(15)},
+		{Id: "trans_1", Account: "acc_1", Date: day, CreatedAt: day.Add(time.Hour), Amount: decimal.NewFromInt(10), Balance: decimal.NewFromInt(10)},
+	}
+
+	// Transactions on the same date are ordered by when they were created, whatever order they are read in.
+	var buf bytes.Buffer
+	if err := WriteOFX(&buf, Slice(transactions), OFXOptions{}); err != nil {
+		t.Fatalf("WriteOFX returned err %v", err)
+	}
+	if !strings.Contains(buf.String(), "<BALAMT>15</BALAMT>") {
+		t.Fatalf("expected ledger balance 15, actual\n%s", buf.String())
+	}
+
+	// The account's balance is used when it is known.
+	account := akahu.AccountResponse{ID: "acc_1"}
+	account.Balance.Current = decimal.NewFromInt(42)
+	account.Refreshed.Balance = day.Add(3 * time.Hour)
+	buf.Reset()
+	opts := OFXOptions{Accounts: map[akahu.AccountID]OFXAccount{"acc_1": OFXAccountFrom(account)}, Location: time.UTC}
+	if err := WriteOFX(&buf, Slice(transactions), opts); err != nil {
+		t.Fatalf("WriteOFX returned err %v", err)
+	}
+	for _, expected := range []string{"<BALAMT>42</BALAMT>", "<DTASOF>20230402030000.000[+0:UTC]</DTASOF>"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected OFX to contain %s, actual\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestWriteQIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteQIF(&buf, Slice(testTransactions()), QIFOptions{}); err != nil {
+		t.Fatalf("WriteQIF returned err %v", err)
+	}
+
+	expected := "!Type:Bank\n" +
+		"D01/04/2023\nT-12.345\nPCafe Central\nMCAFE CENTRAL WELLINGTON Lunch Cafe Ref123\nLCafes and restaurants\nNRef123\n^\n" +
+		"D02/04/2023\nT2500\nPSALARY\n^\n"
+	if actual := buf.String(); actual != expected {
+		t.Fatalf("expected\n%s\nactual\n%s", expected, actual)
+	}
+}
+
+func TestWriteXero(t *testing.T) {
+	transactions := testTransactions()
+	amount, rate, currency := decimal.RequireFromString("-7.50"), decimal.RequireFromString("0.6075"), "USD"
+	transactions[1].Meta = &akahu.Meta{Conversion: &akahu.Conversion{Amount: &amount, Currency: &currency, Rate: &rate}}
+
+	var buf bytes.Buffer
+	if err := WriteXero(&buf, Slice(transactions)); err != nil {
+		t.Fatalf("WriteXero returned err %v", err)
+	}
+
+	expected := "*Date,*Amount,Payee,Description,Reference,Particulars,Code,Transaction Type,Foreign Amount,Foreign Currency,Exchange Rate\n" +
+		"01/04/2023,-12.345,Cafe Central,CAFE CENTRAL WELLINGTON,Ref123,Lunch,Cafe,EFTPOS,,,\n" +
+		"02/04/2023,2500,SALARY,SALARY,,,,CREDIT,-7.5,USD,0.6075\n"
+	if actual := buf.String(); actual != expected {
+		t.Fatalf("expected\n%s\nactual\n%s", expected, actual)
+	}
+}
+
+func TestWriteCSV_Formulas(t *testing.T) {
+	transactions := testTransactions()[:1]
+	transactions[0].Description = `=HYPERLINK("https://example.com","Click")`
+	particulars, code, reference := "@SUM(A1:A2)", "+1", "-1+1"
+	transactions[0].Meta.Particulars, transactions[0].Meta.Code, transactions[0].Meta.Reference = &particulars, &code, &reference
+
+	var buf bytes.Buffer
+	columns := []Column{ColumnDescription, ColumnAmount, ColumnParticulars, ColumnCode, ColumnReference, ColumnType}
+	if err := WriteCSV(&buf, Slice(transactions), columns); err != nil {
+		t.Fatalf("WriteCSV returned err %v", err)
+	}
+
+	expected := "Description,Amount,Particulars,Code,Reference,Type\n" +
+		`"'=HYPERLINK(""https://example.com"",""Click"")",-12.345,'@SUM(A1:A2),+1,'-1+1,EFTPOS` + "\n"
+	if actual := buf.String(); actual != expected {
+		t.Fatalf("expected\n%s\nactual\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,306 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ofxHeader     = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n" + `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	ofxDateLayout = "20060102150405.000"
+	ofxNameLength = 32
+)
+
+// OFXAccount identifies the bank account that a statement belongs to.
+type OFXAccount struct {
+	BankID    string
+	AccountID string
+	// AccountType is one of CHECKING, SAVINGS, MONEYMRKT or CREDITLINE. Defaults to CHECKING.
+	AccountType string
+	// Currency defaults to NZD.
+	Currency string
+	// Balance, if set, is written as the statement's ledger balance as of BalanceAsOf. Otherwise the balance after
+	// the statement's last transaction is used.
+	Balance     *decimal.Decimal
+	BalanceAsOf time.Time
+}
+
+// OFXAccountFrom creates an OFXAccount from an Akahu account, splitting a NZ formatted account number
+// (e.g. 12-3456-7890123-00) into the bank and branch, and account and suffix. The account's current balance is used
+// as the ledger balance if Akahu has refreshed it.
+func OFXAccountFrom(account akahu.AccountResponse) OFXAccount {
+	ofxAccount := OFXAccount{
+		AccountID: string(account.ID),
+		Currency:  account.Balance.Currency,
+	}
+	if !account.Refreshed.Balance.IsZero() {
+		balance := account.Balance.Current
+		ofxAccount.Balance = &balance
+		ofxAccount.BalanceAsOf = account.Refreshed.Balance
+	}
+
+	if parts := strings.Split(account.FormattedAccount, "-"); len(parts) == 4 {
+		ofxAccount.BankID = parts[0] + parts[1]
+		ofxAccount.AccountID = parts[2] + parts[3]
+	}
+
+	switch account.Type {
+	case "SAVINGS", "KIWISAVER", "TERMDEPOSIT":
+		ofxAccount.AccountType = "SAVINGS"
+	case "CREDITCARD", "LOAN":
+		ofxAccount.AccountType = "CREDITLINE"
+	}
+
+	return ofxAccount
+}
+
+// OFXOptions configures WriteOFX.
+type OFXOptions struct {
+	// Accounts maps Akahu account IDs to the bank account details written in each statement.
+	// Accounts that aren't in the map are identified by their Akahu account ID.
+	Accounts map[akahu.AccountID]OFXAccount
+	// Start and End are the period covered by the statements. They default to the dates of the first and last transaction.
+	Start time.Time
+	End   time.Time
+	// Location is the time zone dates are written in. Defaults to NZ.
+	Location *time.Location
+}
+
+type ofx struct {
+	XMLName xml.Name `xml:"OFX"`
+	Signon  struct {
+		Response struct {
+			Status   ofxStatus `xml:"STATUS"`
+			Server   string    `xml:"DTSERVER"`
+			Language string    `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Statements []ofxStatementResponse `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxStatementResponse struct {
+	TransactionUID string    `xml:"TRNUID"`
+	Status         ofxStatus `xml:"STATUS"`
+	Statement      struct {
+		Currency string `xml:"CURDEF"`
+		Account  struct {
+			BankID      string `xml:"BANKID"`
+			AccountID   string `xml:"ACCTID"`
+			AccountType string `xml:"ACCTTYPE"`
+		} `xml:"BANKACCTFROM"`
+		TransactionList struct {
+			Start        string           `xml:"DTSTART"`
+			End          string           `xml:"DTEND"`
+			Transactions []ofxTransaction `xml:"STMTTRN"`
+		} `xml:"BANKTRANLIST"`
+		LedgerBalance *struct {
+			Amount string `xml:"BALAMT"`
+			AsOf   string `xml:"DTASOF"`
+		} `xml:"LEDGERBAL,omitempty"`
+	} `xml:"STMTRS"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	ID     string `xml:"FITID"`
+	Name   string `xml:"NAME,omitempty"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+// WriteOFX writes an OFX 2.2 document with a bank statement for each account that the transactions belong to.
+// Transactions are read into memory, as the statement period and balance are written before and after them.
+func WriteOFX(w io.Writer, it Iterator, opts OFXOptions) error {
+	transactions, err := collect(it)
+	if err != nil {
+		return err
+	}
+
+	loc := location(opts.Location)
+	formatDate := func(t time.Time) string {
+		return formatOFXDate(t.In(loc))
+	}
+
+	var accountIDs []akahu.AccountID
+	byAccount := map[akahu.AccountID][]akahu.TransactionResponse{}
+	for _, t := range transactions {
+		if _, ok := byAccount[t.Account]; !ok {
+			accountIDs = append(accountIDs, t.Account)
+		}
+		byAccount[t.Account] = append(byAccount[t.Account], t)
+	}
+
+	var doc ofx
+	doc.Signon.Response.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	doc.Signon.Response.Server = formatDate(time.Now())
+	doc.Signon.Response.Language = "ENG"
+
+	for i, accountID := range accountIDs {
+		accountTransactions := byAccount[accountID]
+		// Transactions on the same date are ordered by when Akahu created them, then by ID, so that the last one, whose
+		// balance is the ledger balance, is always the same.
+		sort.SliceStable(accountTransactions, func(i, j int) bool {
+			a, b := accountTransactions[i], accountTransactions[j]
+			switch {
+			case !a.Date.Equal(b.Date):
+				return a.Date.Before(b.Date)
+			case !a.CreatedAt.Equal(b.CreatedAt):
+				return a.CreatedAt.Before(b.CreatedAt)
+			default:
+				return a.Id < b.Id
+			}
+		})
+		first, last := accountTransactions[0], accountTransactions[len(accountTransactions)-1]
+
+		account, ok := opts.Accounts[accountID]
+		if !ok {
+			account = OFXAccount{AccountID: string(accountID)}
+		}
+		if account.AccountType == "" {
+			account.AccountType = "CHECKING"
+		}
+		if account.Currency == "" {
+			account.Currency = "NZD"
+		}
+		if account.BankID == "" {
+			account.BankID = "0"
+		}
+
+		var statement ofxStatementResponse
+		statement.TransactionUID = strconv.Itoa(i + 1)
+		statement.Status = ofxStatus{Code: 0, Severity: "INFO"}
+		statement.Statement.Currency = account.Currency
+		statement.Statement.Account.BankID = account.BankID
+		statement.Statement.Account.AccountID = account.AccountID
+		statement.Statement.Account.AccountType = account.AccountType
+
+		start, end := opts.Start, opts.End
+		if start.IsZero() {
+			start = first.Date
+		}
+		if end.IsZero() {
+			end = last.Date
+		}
+		statement.Statement.TransactionList.Start = formatDate(start)
+		statement.Statement.TransactionList.End = formatDate(end)
+
+		for _, t := range accountTransactions {
+			statement.Statement.TransactionList.Transactions = append(statement.Statement.TransactionList.Transactions, ofxTransaction{
+				Type:   ofxTransactionType(t),
+				Posted: formatDate(t.Date),
+				Amount: t.Amount.String(),
+				ID:     string(t.Id),
+				Name:   truncate(payee(t), ofxNameLength),
+				Memo:   ofxMemo(t),
+			})
+		}
+
+		balance, asOf := last.Balance, last.Date
+		if account.Balance != nil {
+			balance, asOf = *account.Balance, account.BalanceAsOf
+		}
+		statement.Statement.LedgerBalance = &struct {
+			Amount string `xml:"BALAMT"`
+			AsOf   string `xml:"DTASOF"`
+		}{Amount: balance.String(), AsOf: formatDate(asOf)}
+
+		doc.Statements = append(doc.Statements, statement)
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// formatOFXDate formats t with its offset from UTC in hours and its time zone's abbreviation, e.g.
+// 20230401003000.000[+13:NZDT], or 20230401061500.000[+5.75:NPT] for a zone that isn't a whole number of hours ahead.
+// Go's layouts can only write the offset in hours and minutes, which OFX readers take as a number of hours.
+func formatOFXDate(t time.Time) string {
+	name, offset := t.Zone()
+
+	hours := strconv.FormatFloat(float64(offset)/3600, 'f', -1, 64)
+	if offset >= 0 {
+		hours = "+" + hours
+	}
+
+	// Zones without an abbreviation are named by their offset, such as +0545, which would only repeat it.
+	if name == "" || strings.ContainsAny(name[:1], "+-0123456789") {
+		return t.Format(ofxDateLayout) + "[" + hours + "]"
+	}
+
+	return t.Format(ofxDateLayout) + "[" + hours + ":" + name + "]"
+}
+
+// ofxTransactionType maps Akahu's transaction types to OFX, falling back to CREDIT or DEBIT by the sign of the amount.
+func ofxTransactionType(t akahu.TransactionResponse) string {
+	switch t.Type {
+	case "EFTPOS":
+		return "POS"
+	case "ATM":
+		return "ATM"
+	case "INTEREST":
+		return "INT"
+	case "FEE":
+		return "FEE"
+	case "TRANSFER":
+		return "XFER"
+	case "STANDING ORDER":
+		return "REPEATPMT"
+	case "DIRECT DEBIT":
+		return "DIRECTDEBIT"
+	case "DIRECT CREDIT":
+		return "DIRECTDEP"
+	case "PAYMENT":
+		return "PAYMENT"
+	}
+
+	if t.Amount.IsNegative() {
+		return "DEBIT"
+	}
+
+	return "CREDIT"
+}
+
+// ofxMemo is the description, when it isn't already used as the name, followed by the particulars, code and reference.
+func ofxMemo(t akahu.TransactionResponse) string {
+	var parts []string
+	if payee(t) != t.Description {
+		parts = append(parts, t.Description)
+	}
+	if m := memo(t); m != "" {
+		parts = append(parts, m)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+
+	return string(runes[:length])
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// QIFOptions configures WriteQIF.
+type QIFOptions struct {
+	// DateLayout is the layout dates are written with. Defaults to "02/01/2006", the day first format used in New Zealand.
+	DateLayout string
+	// Location is the time zone dates are written in. Defaults to NZ.
+	Location *time.Location
+}
+
+// WriteQIF writes the transactions as a QIF bank account register.
+// The payee is the merchant name if known, otherwise the description, and the particulars, code and reference are written as the memo.
+func WriteQIF(w io.Writer, it Iterator, opts QIFOptions) error {
+	layout := opts.DateLayout
+	if layout == "" {
+		layout = "02/01/2006"
+	}
+	loc := location(opts.Location)
+
+	bw := bufio.NewWriter(w)
+	_, _ = bw.WriteString("!Type:Bank\n")
+
+	for it.Next() {
+		t := it.Transaction()
+
+		writeQIFField(bw, 'D', t.Date.In(loc).Format(layout))
+		writeQIFField(bw, 'T', t.Amount.String())
+		writeQIFField(bw, 'P', payee(t))
+		writeQIFField(bw, 'M', ofxMemo(t))
+		writeQIFField(bw, 'L', categoryName(t))
+		writeQIFField(bw, 'N', reference(t))
+		_, _ = bw.WriteString("^\n")
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func writeQIFField(w *bufio.Writer, code byte, value string) {
+	if value == "" {
+		return
+	}
+
+	_ = w.WriteByte(code)
+	_, _ = w.WriteString(value)
+	_ = w.WriteByte('\n')
+}
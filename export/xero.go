@@ -0,0 +1,68 @@
+package export
+
+import (
+	"io"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// XeroDateLayout is the day first date format that Xero expects for NZ organisations.
+const XeroDateLayout = "02/01/2006"
+
+// XeroColumns are the columns of Xero's bank statement CSV import. Column headers that start with * are required by Xero.
+//
+// Foreign currency transactions have the amount in the original currency, the currency and the exchange rate from
+// Meta.Conversion in their own columns, while Amount is always in the account's currency.
+var XeroColumns = []Column{
+	DateColumn("*Date", XeroDateLayout, nil),
+	{"*Amount", func(t akahu.TransactionResponse) string { return t.Amount.String() }},
+	ColumnPayee,
+	ColumnDescription,
+	ColumnReference,
+	ColumnParticulars,
+	ColumnCode,
+	{"Transaction Type", func(t akahu.TransactionResponse) string { return t.Type }},
+	{"Foreign Amount", foreignAmount},
+	{"Foreign Currency", foreignCurrency},
+	{"Exchange Rate", exchangeRate},
+}
+
+// WriteXero writes the transactions as a Xero bank statement CSV, with dates in NZ time.
+func WriteXero(w io.Writer, it Iterator) error {
+	return WriteCSV(w, it, XeroColumns)
+}
+
+func conversion(t akahu.TransactionResponse) *akahu.Conversion {
+	if t.Meta == nil {
+		return nil
+	}
+
+	return t.Meta.Conversion
+}
+
+func foreignAmount(t akahu.TransactionResponse) string {
+	c := conversion(t)
+	if c == nil || c.Amount == nil {
+		return ""
+	}
+
+	return c.Amount.String()
+}
+
+func foreignCurrency(t akahu.TransactionResponse) string {
+	c := conversion(t)
+	if c == nil {
+		return ""
+	}
+
+	return stringOrEmpty(c.Currency)
+}
+
+func exchangeRate(t akahu.TransactionResponse) string {
+	c := conversion(t)
+	if c == nil || c.Rate == nil {
+		return ""
+	}
+
+	return c.Rate.String()
+}
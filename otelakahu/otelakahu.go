@@ -0,0 +1,162 @@
+// Package otelakahu provides OpenTelemetry tracing and metrics for the Akahu API client.
+//
+// It is a separate module so that the akahu package does not depend on OpenTelemetry.
+// Register the hook on a client to wrap every service call in a span:
+//
+//	client := akahu.NewClient(nil, appToken, appSecret, redirectURI)
+//	hook, err := otelakahu.NewHook()
+//	if err != nil {
+//		return err
+//	}
+//	client.Hooks = append(client.Hooks, hook)
+package otelakahu
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/jdebes/akahu-sdk-go/otelakahu"
+
+const (
+	operationKey   = attribute.Key("akahu.operation")
+	endpointKey    = attribute.Key("akahu.endpoint")
+	resultCountKey = attribute.Key("akahu.result_count")
+	methodKey      = attribute.Key("http.request.method")
+	statusCodeKey  = attribute.Key("http.response.status_code")
+	errorTypeKey   = attribute.Key("error.type")
+)
+
+// Option configures the hook returned by NewHook.
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans. The global provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the MeterProvider used to record metrics. The global provider is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+// Hook is an akahu.RequestHook that records a span and metrics for each Akahu API call.
+type Hook struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Float64Histogram
+}
+
+var _ akahu.RequestHook = (*Hook)(nil)
+
+// NewHook creates a Hook that records:
+//   - a client span per call, named after the operation (e.g. "akahu.Transactions.List"),
+//     with the endpoint, status code and result count as attributes,
+//   - the "akahu.client.request.duration" histogram for every call,
+//   - the "akahu.client.request.error.duration" histogram for calls that failed or returned an unsuccessful status code.
+func NewHook(opts ...Option) (*Hook, error) {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+
+	duration, err := meter.Float64Histogram(
+		"akahu.client.request.duration",
+		metric.WithDescription("Duration of Akahu API requests."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	errorDuration, err := meter.Float64Histogram(
+		"akahu.client.request.error.duration",
+		metric.WithDescription("Duration of Akahu API requests that failed or returned an unsuccessful status code."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hook{
+		tracer:   c.tracerProvider.Tracer(instrumentationName),
+		duration: duration,
+		errors:   errorDuration,
+	}, nil
+}
+
+// BeforeRequest starts the span for the call.
+func (h *Hook) BeforeRequest(ctx context.Context, info *akahu.RequestInfo) context.Context {
+	ctx, _ = h.tracer.Start(ctx, info.Operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			operationKey.String(info.Operation),
+			methodKey.String(info.Method),
+			endpointKey.String(info.Endpoint),
+		),
+	)
+
+	return ctx
+}
+
+// AfterRequest ends the span for the call and records its duration.
+func (h *Hook) AfterRequest(ctx context.Context, info *akahu.RequestInfo) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	// The endpoint is left out of the metric attributes as it contains IDs, which would blow out cardinality.
+	attrs := []attribute.KeyValue{
+		operationKey.String(info.Operation),
+		methodKey.String(info.Method),
+	}
+	if info.StatusCode != 0 {
+		attrs = append(attrs, statusCodeKey.Int(info.StatusCode))
+	}
+
+	span.SetAttributes(resultCountKey.Int(info.ResultCount))
+	if info.StatusCode != 0 {
+		span.SetAttributes(statusCodeKey.Int(info.StatusCode))
+	}
+
+	failed := false
+	switch {
+	case info.Err != nil:
+		failed = true
+		span.RecordError(info.Err)
+		span.SetStatus(codes.Error, info.Err.Error())
+		attrs = append(attrs, errorTypeKey.String("error"))
+	case info.StatusCode >= http.StatusBadRequest:
+		failed = true
+		span.SetStatus(codes.Error, http.StatusText(info.StatusCode))
+		attrs = append(attrs, errorTypeKey.String(strconv.Itoa(info.StatusCode)))
+	}
+
+	set := metric.WithAttributes(attrs...)
+	seconds := info.Duration.Seconds()
+	h.duration.Record(ctx, seconds, set)
+	if failed {
+		h.errors.Record(ctx, seconds, set)
+	}
+}
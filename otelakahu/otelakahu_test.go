@@ -0,0 +1,133 @@
+package otelakahu
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHook(t *testing.T) {
+	tests := []struct {
+		name                string
+		info                akahu.RequestInfo
+		expectedStatus      codes.Code
+		expectedErrorPoints uint64
+		expectedErrorType   string
+	}{
+		{
+			name: "with successful request",
+			info: akahu.RequestInfo{
+				Operation:   "akahu.Transactions.List",
+				Method:      "GET",
+				Endpoint:    "/v1/transactions",
+				StatusCode:  200,
+				ResultCount: 3,
+				Duration:    time.Second,
+			},
+			expectedStatus:      codes.Unset,
+			expectedErrorPoints: 0,
+		},
+		{
+			name: "with unsuccessful status code",
+			info: akahu.RequestInfo{
+				Operation:  "akahu.Transactions.List",
+				Method:     "GET",
+				Endpoint:   "/v1/transactions",
+				StatusCode: 401,
+				Duration:   time.Second,
+			},
+			expectedStatus:      codes.Error,
+			expectedErrorPoints: 1,
+			expectedErrorType:   "401",
+		},
+		{
+			name: "with transport error",
+			info: akahu.RequestInfo{
+				Operation: "akahu.Transactions.List",
+				Method:    "GET",
+				Endpoint:  "/v1/transactions",
+				Duration:  time.Second,
+				Err:       errors.New("connection reset"),
+			},
+			expectedStatus:      codes.Error,
+			expectedErrorPoints: 1,
+			expectedErrorType:   "error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spans := tracetest.NewSpanRecorder()
+			reader := sdkmetric.NewManualReader()
+
+			hook, err := NewHook(
+				WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+				WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+			)
+			if err != nil {
+				t.Fatalf("NewHook returned err %v", err)
+			}
+
+			info := test.info
+			ctx := hook.BeforeRequest(context.Background(), &info)
+			hook.AfterRequest(ctx, &info)
+
+			ended := spans.Ended()
+			if len(ended) != 1 {
+				t.Fatalf("expected 1 span, actual %d", len(ended))
+			}
+
+			span := ended[0]
+			if span.Name() != test.info.Operation {
+				t.Fatalf("expected span name %s, actual %s", test.info.Operation, span.Name())
+			}
+			if span.Status().Code != test.expectedStatus {
+				t.Fatalf("expected span status %v, actual %v", test.expectedStatus, span.Status().Code)
+			}
+
+			attrs := attribute.NewSet(span.Attributes()...)
+			if v, _ := attrs.Value(endpointKey); v.AsString() != test.info.Endpoint {
+				t.Fatalf("expected endpoint attribute %s, actual %s", test.info.Endpoint, v.AsString())
+			}
+			if v, _ := attrs.Value(resultCountKey); v.AsInt64() != int64(test.info.ResultCount) {
+				t.Fatalf("expected result count attribute %d, actual %d", test.info.ResultCount, v.AsInt64())
+			}
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("collect returned err %v", err)
+			}
+
+			counts := map[string]uint64{}
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+						counts[m.Name] += dp.Count
+
+						// error.type is always a string, so that metrics can be grouped by it.
+						v, ok := dp.Attributes.Value(errorTypeKey)
+						if ok != (test.expectedErrorType != "") || (ok && (v.Type() != attribute.STRING || v.AsString() != test.expectedErrorType)) {
+							t.Fatalf("expected error type attribute %q, actual %v", test.expectedErrorType, v.Emit())
+						}
+					}
+				}
+			}
+
+			if counts["akahu.client.request.duration"] != 1 {
+				t.Fatalf("expected 1 duration point, actual %d", counts["akahu.client.request.duration"])
+			}
+			if counts["akahu.client.request.error.duration"] != test.expectedErrorPoints {
+				t.Fatalf("expected %d error points, actual %d", test.expectedErrorPoints, counts["akahu.client.request.error.duration"])
+			}
+		})
+	}
+}
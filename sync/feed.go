@@ -0,0 +1,105 @@
+package sync
+
+import (
+	stdsync "sync"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// ChangeType is the kind of change made to a transaction.
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "ADDED"
+	ChangeUpdated ChangeType = "UPDATED"
+	ChangeRemoved ChangeType = "REMOVED"
+)
+
+// Change is a change made to one of a user's settled transactions.
+type Change struct {
+	UserID string
+	Type   ChangeType
+	// Transaction is the transaction after the change. For a removed transaction it is the stored transaction,
+	// or only has its Id set if the transaction was never stored.
+	Transaction akahu.TransactionResponse
+}
+
+// Feed publishes changes to its subscribers, in the order they were made.
+// The zero value is ready to use.
+type Feed struct {
+	// OnDrop, if set, is called with each change that a subscriber missed because its buffer was full.
+	OnDrop func(Change)
+
+	mu          stdsync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	mu     stdsync.Mutex
+	ch     chan Change
+	closed bool
+}
+
+// Subscribe returns a channel that receives every change published after it was created, and a function that
+// unsubscribes and closes the channel. Publishing never waits for a subscriber: if the channel's buffer is full the
+// change is dropped for that subscriber and passed to OnDrop, so the buffer should cover the largest burst of changes
+// the subscriber may fall behind by.
+func (f *Feed) Subscribe(buffer int) (<-chan Change, func()) {
+	sub := &subscriber{ch: make(chan Change, buffer)}
+
+	f.mu.Lock()
+	if f.subscribers == nil {
+		f.subscribers = map[*subscriber]struct{}{}
+	}
+	f.subscribers[sub] = struct{}{}
+	f.mu.Unlock()
+
+	var once stdsync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subscribers, sub)
+			f.mu.Unlock()
+
+			sub.mu.Lock()
+			sub.closed = true
+			close(sub.ch)
+			sub.mu.Unlock()
+		})
+	}
+}
+
+func (f *Feed) publish(changes []Change) {
+	f.mu.RLock()
+	subscribers := make([]*subscriber, 0, len(f.subscribers))
+	for sub := range f.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	f.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		for _, change := range changes {
+			if !sub.send(change) && f.OnDrop != nil {
+				f.OnDrop(change)
+			}
+		}
+	}
+}
+
+// send delivers change without blocking, reporting false if it was dropped. The lock stops it sending on a channel
+// closed by unsubscribing, and as sends never block, holding it can't hold up unsubscribing.
+func (s *subscriber) send(change Change) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return true
+	}
+
+	select {
+	case s.ch <- change:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,63 @@
+package sync
+
+import (
+	stdsync "sync"
+	"testing"
+	"time"
+)
+
+func TestFeed_DropsWhenFull(t *testing.T) {
+	var dropped []Change
+	feed := &Feed{OnDrop: func(c Change) { dropped = append(dropped, c) }}
+
+	changes, unsubscribe := feed.Subscribe(1)
+	defer unsubscribe()
+
+	// Nothing is reading, so only the first change fits and publishing doesn't wait for the rest.
+	feed.publish([]Change{{UserID: "user_1"}, {UserID: "user_2"}, {UserID: "user_3"}})
+
+	if c := <-changes; c.UserID != "user_1" {
+		t.Fatalf("expected change for user_1, actual %v", c)
+	}
+	if len(dropped) != 2 || dropped[0].UserID != "user_2" || dropped[1].UserID != "user_3" {
+		t.Fatalf("expected changes for user_2 and user_3 to be dropped, actual %v", dropped)
+	}
+}
+
+func TestFeed_UnsubscribeWhilePublishing(t *testing.T) {
+	feed := &Feed{}
+	done := make(chan struct{})
+
+	var wg stdsync.WaitGroup
+	var unsubscribes []func()
+	for i := 0; i < 10; i++ {
+		changes, unsubscribe := feed.Subscribe(1)
+		unsubscribes = append(unsubscribes, unsubscribe)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Unsubscribe from the reading goroutine while changes are still being published.
+			for range changes {
+				unsubscribe()
+			}
+		}()
+	}
+
+	go func() {
+		for i := 0; i < 1000; i++ {
+			feed.publish([]Change{{UserID: "user_1"}})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("publishing blocked on subscribers that unsubscribed")
+	}
+
+	for _, unsubscribe := range unsubscribes {
+		unsubscribe()
+	}
+	wg.Wait()
+}
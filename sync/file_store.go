@@ -0,0 +1,141 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	stdsync "sync"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// FileStore is a Store that keeps each user's transactions in a JSON file in a directory.
+// Files are replaced atomically, so a crash during a sync never leaves a user's transactions partially written.
+type FileStore struct {
+	mu  stdsync.Mutex
+	dir string
+}
+
+// NewFileStore creates a FileStore that keeps its files in dir, which is created if it doesn't exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) HighWaterMark(_ context.Context, userID string) (time.Time, error) {
+	data, err := s.read(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return data.HighWaterMark, nil
+}
+
+func (s *FileStore) Transactions(_ context.Context, userID string, ids []akahu.TransactionID) (map[akahu.TransactionID]akahu.TransactionResponse, error) {
+	data, err := s.read(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.lookup(ids), nil
+}
+
+func (s *FileStore) List(_ context.Context, userID string) ([]akahu.TransactionResponse, error) {
+	data, err := s.read(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.list(), nil
+}
+
+func (s *FileStore) Pending(_ context.Context, userID string) ([]akahu.TransactionResponse, error) {
+	data, err := s.read(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.pending(), nil
+}
+
+func (s *FileStore) Apply(_ context.Context, userID string, changes Changes) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.load(userID)
+	if err != nil {
+		return err
+	}
+	data.apply(changes)
+
+	return s.write(userID, data)
+}
+
+func (s *FileStore) read(userID string) (*userData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(userID)
+}
+
+func (s *FileStore) load(userID string) (*userData, error) {
+	data := newUserData()
+
+	b, err := os.ReadFile(s.path(userID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, data); err != nil {
+		return nil, err
+	}
+	if data.Transactions == nil {
+		data.Transactions = map[akahu.TransactionID]akahu.TransactionResponse{}
+	}
+
+	return data, nil
+}
+
+func (s *FileStore) write(userID string, data *userData) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	path := s.path(userID)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// path escapes the user ID so that it can't point outside of the store's directory.
+func (s *FileStore) path(userID string) string {
+	return filepath.Join(s.dir, "user-"+url.PathEscape(userID)+".json")
+}
@@ -0,0 +1,211 @@
+package sync
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultSettleWindow  = 7 * 24 * time.Hour
+	defaultMinSimilarity = 0.5
+	// settleLeeway allows for a settled transaction to be dated slightly before its pending transaction, which
+	// happens when the bank dates the pending transaction by when it was processed rather than made.
+	settleLeeway = 24 * time.Hour
+)
+
+// ReconcileOptions configures how pending transactions are matched to settled transactions.
+type ReconcileOptions struct {
+	// SettleWindow is how long after a pending transaction's date it may settle. Defaults to 7 days.
+	SettleWindow time.Duration
+	// MinSimilarity is how similar, from 0 to 1, the descriptions of a pending and settled transaction must be to match.
+	// Defaults to 0.5.
+	MinSimilarity float64
+	// AmountTolerance is how much the settled amount may differ from the pending amount, e.g. for a tip added
+	// after a card was authorised. Defaults to an exact match.
+	AmountTolerance decimal.Decimal
+}
+
+// Match is a pending transaction that has settled.
+type Match struct {
+	Pending akahu.TransactionResponse
+	Settled akahu.TransactionResponse
+	// Similarity of the descriptions, from 0 to 1.
+	Similarity float64
+}
+
+// Reconciliation is the outcome of matching pending transactions to settled transactions.
+type Reconciliation struct {
+	// Matched are pending transactions that have settled, and should no longer be shown as pending.
+	Matched []Match
+	// StillPending are pending transactions that haven't settled yet.
+	StillPending []akahu.TransactionResponse
+	// Dropped are pending transactions that disappeared without settling, such as an expired or reversed card authorisation.
+	Dropped []akahu.TransactionResponse
+}
+
+// Reconcile matches pending transactions to the settled transactions they became.
+//
+// previous are the pending transactions from an earlier sync, current are the pending transactions now, and settled
+// are the settled transactions in the range they were fetched for. A pending transaction matches a settled transaction in the same account
+// with the same amount, dated within the settle window, and with a similar description. Each settled transaction
+// matches at most one pending transaction.
+//
+// Pending transactions that match are reported as Matched even when Akahu still lists them as pending, as banks
+// often list both for a short time. Of the rest, those in current are StillPending, and those that are not are Dropped.
+func Reconcile(previous, current, settled []akahu.TransactionResponse, opts ReconcileOptions) Reconciliation {
+	if opts.SettleWindow == 0 {
+		opts.SettleWindow = defaultSettleWindow
+	}
+	if opts.MinSimilarity == 0 {
+		opts.MinSimilarity = defaultMinSimilarity
+	}
+
+	currentKeys := map[pendingKey]int{}
+	for _, t := range current {
+		currentKeys[keyOf(t)]++
+	}
+
+	// Both lists usually contain the same pending transactions, so only consider each once.
+	var candidates []akahu.TransactionResponse
+	seen := map[pendingKey]int{}
+	for _, list := range [][]akahu.TransactionResponse{previous, current} {
+		counts := map[pendingKey]int{}
+		for _, t := range list {
+			key := keyOf(t)
+			counts[key]++
+			if counts[key] > seen[key] {
+				seen[key] = counts[key]
+				candidates = append(candidates, t)
+			}
+		}
+	}
+
+	var reconciliation Reconciliation
+	used := make([]bool, len(settled))
+
+	for _, pending := range candidates {
+		best, bestSimilarity := -1, 0.0
+		for i, s := range settled {
+			if used[i] || !settles(pending, s, opts) {
+				continue
+			}
+
+			similarity := descriptionSimilarity(pending.Description, s.Description)
+			if similarity < opts.MinSimilarity {
+				continue
+			}
+			if best == -1 || similarity > bestSimilarity ||
+				(similarity == bestSimilarity && absDuration(s.Date.Sub(pending.Date)) < absDuration(settled[best].Date.Sub(pending.Date))) {
+				best, bestSimilarity = i, similarity
+			}
+		}
+
+		key := keyOf(pending)
+		switch {
+		case best != -1:
+			used[best] = true
+			reconciliation.Matched = append(reconciliation.Matched, Match{Pending: pending, Settled: settled[best], Similarity: bestSimilarity})
+		case currentKeys[key] > 0:
+			reconciliation.StillPending = append(reconciliation.StillPending, pending)
+		default:
+			reconciliation.Dropped = append(reconciliation.Dropped, pending)
+		}
+		if currentKeys[key] > 0 {
+			currentKeys[key]--
+		}
+	}
+
+	return reconciliation
+}
+
+// pendingKey identifies a pending transaction between syncs, as they have no stable ID.
+type pendingKey struct {
+	account     akahu.AccountID
+	date        int64
+	amount      string
+	description string
+}
+
+func keyOf(t akahu.TransactionResponse) pendingKey {
+	return pendingKey{
+		account:     t.Account,
+		date:        t.Date.UnixNano(),
+		amount:      t.Amount.String(),
+		description: t.Description,
+	}
+}
+
+func settles(pending, settled akahu.TransactionResponse, opts ReconcileOptions) bool {
+	if pending.Account != settled.Account {
+		return false
+	}
+	if pending.Amount.Sub(settled.Amount).Abs().GreaterThan(opts.AmountTolerance.Abs()) {
+		return false
+	}
+
+	return !settled.Date.Before(pending.Date.Add(-settleLeeway)) && !settled.Date.After(pending.Date.Add(opts.SettleWindow))
+}
+
+// descriptionSimilarity is the Sørensen–Dice coefficient of the bigrams of the normalised descriptions.
+// Banks often add or remove card numbers, locations and punctuation when a transaction settles, which this tolerates.
+func descriptionSimilarity(a, b string) float64 {
+	a, b = normaliseDescription(a), normaliseDescription(b)
+	if a == b {
+		return 1
+	}
+
+	bigramsA, bigramsB := bigrams(a), bigrams(b)
+	total := len(bigramsA) + len(bigramsB)
+	if total == 0 {
+		return 0
+	}
+
+	counts := map[string]int{}
+	for _, bigram := range bigramsA {
+		counts[bigram]++
+	}
+
+	shared := 0
+	for _, bigram := range bigramsB {
+		if counts[bigram] > 0 {
+			counts[bigram]--
+			shared++
+		}
+	}
+
+	return 2 * float64(shared) / float64(total)
+}
+
+func normaliseDescription(s string) string {
+	fields := strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return nil
+	}
+
+	bigrams := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		bigrams = append(bigrams, string(runes[i:i+2]))
+	}
+
+	return bigrams
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
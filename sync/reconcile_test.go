@@ -0,0 +1,138 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"github.com/shopspring/decimal"
+)
+
+func pendingTransaction(description string, date time.Time, amount string) akahu.TransactionResponse {
+	return akahu.TransactionResponse{
+		Account:     "acc_1",
+		Date:        date,
+		Description: description,
+		Amount:      decimal.RequireFromString(amount),
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	day10 := day1.Add(9 * 24 * time.Hour)
+
+	coffee := pendingTransaction("COFFEE SUPREME 4835-****-****-1234", day1, "-5.50")
+	groceries := pendingTransaction("COUNTDOWN PONSONBY", day1, "-82.10")
+	fuel := pendingTransaction("Z ENERGY GREY LYNN", day1, "-90.00")
+	hotel := pendingTransaction("HOTEL DEPOSIT", day1, "-200.00")
+
+	settledCoffee := transaction("trans_1", day2, day2, "-5.50")
+	settledCoffee.Description = "Coffee Supreme"
+	// Same amount and account as the groceries, but an unrelated description.
+	settledRent := transaction("trans_2", day2, day2, "-82.10")
+	settledRent.Description = "RENT PAYMENT"
+	// Matching description and amount, but too late to be the fuel transaction settling.
+	settledFuel := transaction("trans_3", day10, day10, "-90.00")
+	settledFuel.Description = "Z ENERGY GREY LYNN"
+
+	tests := []struct {
+		name                 string
+		previous             []akahu.TransactionResponse
+		current              []akahu.TransactionResponse
+		settled              []akahu.TransactionResponse
+		opts                 ReconcileOptions
+		expectedMatched      int
+		expectedStillPending []string
+		expectedDropped      []string
+	}{
+		{
+			name:                 "with settled, still pending and dropped transactions",
+			previous:             []akahu.TransactionResponse{coffee, groceries, fuel, hotel},
+			current:              []akahu.TransactionResponse{groceries},
+			settled:              []akahu.TransactionResponse{settledCoffee, settledRent, settledFuel},
+			expectedMatched:      1,
+			expectedStillPending: []string{groceries.Description},
+			expectedDropped:      []string{fuel.Description, hotel.Description},
+		},
+		{
+			name:            "with transaction listed as both pending and settled",
+			previous:        []akahu.TransactionResponse{coffee},
+			current:         []akahu.TransactionResponse{coffee},
+			settled:         []akahu.TransactionResponse{settledCoffee},
+			expectedMatched: 1,
+		},
+		{
+			name:                 "with settled transaction matching only one of two identical pending transactions",
+			current:              []akahu.TransactionResponse{coffee, coffee},
+			settled:              []akahu.TransactionResponse{settledCoffee},
+			expectedMatched:      1,
+			expectedStillPending: []string{coffee.Description},
+		},
+		{
+			name:            "with amount tolerance",
+			previous:        []akahu.TransactionResponse{pendingTransaction("COFFEE SUPREME", day1, "-5.00")},
+			settled:         []akahu.TransactionResponse{settledCoffee},
+			opts:            ReconcileOptions{AmountTolerance: decimal.RequireFromString("1")},
+			expectedMatched: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := Reconcile(test.previous, test.current, test.settled, test.opts)
+
+			if len(actual.Matched) != test.expectedMatched {
+				t.Fatalf("expected %d matched, actual %v", test.expectedMatched, actual.Matched)
+			}
+			if test.expectedMatched > 0 && actual.Matched[0].Settled.Id != "trans_1" {
+				t.Fatalf("expected match with trans_1, actual %v", actual.Matched[0])
+			}
+			if descriptions := transactionDescriptions(actual.StillPending); !equalStrings(descriptions, test.expectedStillPending) {
+				t.Fatalf("expected still pending %v, actual %v", test.expectedStillPending, descriptions)
+			}
+			if descriptions := transactionDescriptions(actual.Dropped); !equalStrings(descriptions, test.expectedDropped) {
+				t.Fatalf("expected dropped %v, actual %v", test.expectedDropped, descriptions)
+			}
+		})
+	}
+}
+
+func TestDescriptionSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"COFFEE SUPREME 4835-****-****-1234", "Coffee Supreme", true},
+		{"Countdown Ponsonby", "COUNTDOWN PONSONBY AUCKLAND NZ", true},
+		{"COUNTDOWN PONSONBY", "RENT PAYMENT", false},
+	}
+
+	for _, test := range tests {
+		if actual := descriptionSimilarity(test.a, test.b) >= defaultMinSimilarity; actual != test.expected {
+			t.Errorf("expected %q and %q similar %v, actual %v", test.a, test.b, test.expected, actual)
+		}
+	}
+}
+
+func transactionDescriptions(transactions []akahu.TransactionResponse) []string {
+	descriptions := make([]string, len(transactions))
+	for i, t := range transactions {
+		descriptions[i] = t.Description
+	}
+
+	return descriptions
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,161 @@
+package sync
+
+import (
+	"context"
+	"sort"
+	stdsync "sync"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// Store persists each user's synced transactions and high-water mark.
+type Store interface {
+	// HighWaterMark returns the end of the user's last successful sync, or the zero time if they have never been synced.
+	HighWaterMark(ctx context.Context, userID string) (time.Time, error)
+	// Transactions returns the stored settled transactions with the given IDs, keyed by ID. IDs that aren't stored are left out.
+	Transactions(ctx context.Context, userID string, ids []akahu.TransactionID) (map[akahu.TransactionID]akahu.TransactionResponse, error)
+	// List returns all the user's stored settled transactions, ordered by date.
+	List(ctx context.Context, userID string) ([]akahu.TransactionResponse, error)
+	// Pending returns the user's pending transactions from their last sync.
+	Pending(ctx context.Context, userID string) ([]akahu.TransactionResponse, error)
+	// Apply saves the changes from a sync. All changes are saved, or none are.
+	Apply(ctx context.Context, userID string, changes Changes) error
+}
+
+// Changes are the changes made to a user's transactions by a sync.
+type Changes struct {
+	// Upserted are settled transactions that are new or have been updated.
+	Upserted []akahu.TransactionResponse
+	// Deleted are the IDs of settled transactions to remove.
+	Deleted []akahu.TransactionID
+	// Pending replaces the user's pending transactions if ReplacePending is set.
+	// Pending transactions have no stable ID, so they are always replaced as a whole.
+	Pending        []akahu.TransactionResponse
+	ReplacePending bool
+	// HighWaterMark is saved if it is not the zero time.
+	HighWaterMark time.Time
+}
+
+// userData is everything stored for a user, shared by the MemoryStore and FileStore.
+type userData struct {
+	HighWaterMark time.Time                                         `json:"high_water_mark"`
+	Transactions  map[akahu.TransactionID]akahu.TransactionResponse `json:"transactions"`
+	Pending       []akahu.TransactionResponse                       `json:"pending"`
+}
+
+func newUserData() *userData {
+	return &userData{Transactions: map[akahu.TransactionID]akahu.TransactionResponse{}}
+}
+
+func (d *userData) lookup(ids []akahu.TransactionID) map[akahu.TransactionID]akahu.TransactionResponse {
+	found := map[akahu.TransactionID]akahu.TransactionResponse{}
+	for _, id := range ids {
+		if t, ok := d.Transactions[id]; ok {
+			found[id] = t
+		}
+	}
+
+	return found
+}
+
+func (d *userData) list() []akahu.TransactionResponse {
+	transactions := make([]akahu.TransactionResponse, 0, len(d.Transactions))
+	for _, t := range d.Transactions {
+		transactions = append(transactions, t)
+	}
+	sortByDate(transactions)
+
+	return transactions
+}
+
+func (d *userData) pending() []akahu.TransactionResponse {
+	return append([]akahu.TransactionResponse(nil), d.Pending...)
+}
+
+func (d *userData) apply(changes Changes) {
+	for _, t := range changes.Upserted {
+		d.Transactions[t.Id] = t
+	}
+	for _, id := range changes.Deleted {
+		delete(d.Transactions, id)
+	}
+	if changes.ReplacePending {
+		d.Pending = append([]akahu.TransactionResponse(nil), changes.Pending...)
+	}
+	if !changes.HighWaterMark.IsZero() {
+		d.HighWaterMark = changes.HighWaterMark
+	}
+}
+
+// sortByDate orders transactions by date, then ID so that the order is stable.
+func sortByDate(transactions []akahu.TransactionResponse) {
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].Date.Equal(transactions[j].Date) {
+			return transactions[i].Date.Before(transactions[j].Date)
+		}
+
+		return transactions[i].Id < transactions[j].Id
+	})
+}
+
+// MemoryStore is a Store that holds transactions in memory.
+type MemoryStore struct {
+	mu    stdsync.RWMutex
+	users map[string]*userData
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{users: map[string]*userData{}}
+}
+
+func (s *MemoryStore) HighWaterMark(_ context.Context, userID string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.user(userID).HighWaterMark, nil
+}
+
+func (s *MemoryStore) Transactions(_ context.Context, userID string, ids []akahu.TransactionID) (map[akahu.TransactionID]akahu.TransactionResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.user(userID).lookup(ids), nil
+}
+
+func (s *MemoryStore) List(_ context.Context, userID string) ([]akahu.TransactionResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.user(userID).list(), nil
+}
+
+func (s *MemoryStore) Pending(_ context.Context, userID string) ([]akahu.TransactionResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.user(userID).pending(), nil
+}
+
+func (s *MemoryStore) Apply(_ context.Context, userID string, changes Changes) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.users[userID]
+	if !ok {
+		data = newUserData()
+		s.users[userID] = data
+	}
+	data.apply(changes)
+
+	return nil
+}
+
+func (s *MemoryStore) user(userID string) *userData {
+	if data, ok := s.users[userID]; ok {
+		return data
+	}
+
+	return newUserData()
+}
@@ -0,0 +1,158 @@
+// Package sync keeps a local copy of each user's Akahu transactions up to date, without re-fetching their
+// whole history on every run.
+//
+// Each sync fetches the transactions dated from shortly before the user's high-water mark, the end of their last
+// successful sync, so that transactions the bank adds or updates late are still picked up. Settled transactions are
+// deduplicated by ID, and UpdatedAt is used to tell which have changed. Pending transactions have no stable ID, and
+// are replaced on every sync.
+package sync
+
+import (
+	"context"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+const (
+	defaultOverlap       = 7 * 24 * time.Hour
+	defaultInitialWindow = 90 * 24 * time.Hour
+)
+
+// Syncer syncs users' transactions from Akahu to a Store.
+type Syncer struct {
+	client *akahu.Client
+	store  Store
+
+	// Overlap is how far before the high-water mark each sync starts. Defaults to 7 days.
+	Overlap time.Duration
+	// InitialWindow is how far back the first sync for a user goes. Defaults to 90 days.
+	InitialWindow time.Duration
+	// Reconcile configures how the user's pending transactions are matched to the settled transactions they become.
+	Reconcile ReconcileOptions
+
+	now func() time.Time
+}
+
+// NewSyncer creates a Syncer that fetches transactions with client and saves them to store.
+func NewSyncer(client *akahu.Client, store Store) *Syncer {
+	return &Syncer{
+		client:        client,
+		store:         store,
+		Overlap:       defaultOverlap,
+		InitialWindow: defaultInitialWindow,
+		now:           time.Now,
+	}
+}
+
+// Result is the outcome of syncing a user.
+type Result struct {
+	// Added are settled transactions that weren't in the store.
+	Added []akahu.TransactionResponse
+	// Updated are settled transactions that were in the store, but have been updated since.
+	Updated []akahu.TransactionResponse
+	// Unchanged is the number of fetched settled transactions that were already in the store.
+	Unchanged int
+	// Pending are the user's pending transactions.
+	Pending []akahu.TransactionResponse
+	// Reconciliation matches the pending transactions from the user's previous sync and this one to every settled
+	// transaction fetched, including those already stored by an earlier sync in the overlap, so that a transaction
+	// isn't shown as both pending and settled.
+	Reconciliation Reconciliation
+	// Start and End are the date range that was fetched. End is the user's new high-water mark.
+	Start time.Time
+	End   time.Time
+}
+
+// Sync fetches the user's settled and pending transactions since their high-water mark and saves any changes.
+//
+// If Akahu returns an unsuccessful response nothing is saved, and the response is returned with a nil Result.
+func (s *Syncer) Sync(ctx context.Context, userID, userAccessToken string) (*Result, *akahu.APIResponse, error) {
+	highWaterMark, err := s.store.HighWaterMark(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	end := s.now().UTC()
+	start := end.Add(-s.InitialWindow)
+	if !highWaterMark.IsZero() {
+		start = highWaterMark.Add(-s.Overlap)
+	}
+
+	settled, res, err := s.client.Transactions.List(ctx, userAccessToken, start, end)
+	if err != nil || !res.Success {
+		return nil, res, err
+	}
+
+	pending, res, err := s.client.Transactions.ListPending(ctx, userAccessToken, start, end)
+	if err != nil || !res.Success {
+		return nil, res, err
+	}
+
+	result := &Result{Pending: pending, Start: start, End: end}
+
+	settled = dedupe(settled)
+	ids := make([]akahu.TransactionID, len(settled))
+	for i, t := range settled {
+		ids[i] = t.Id
+	}
+
+	stored, err := s.store.Transactions(ctx, userID, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	previousPending, err := s.store.Pending(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, t := range settled {
+		existing, ok := stored[t.Id]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, t)
+		case t.UpdatedAt.After(existing.UpdatedAt):
+			result.Updated = append(result.Updated, t)
+		default:
+			result.Unchanged++
+		}
+	}
+
+	// Reconcile against every settled transaction in the range, not only the added ones, as a transaction that settled
+	// in an earlier sync may still be listed as pending.
+	result.Reconciliation = Reconcile(previousPending, pending, settled, s.Reconcile)
+
+	changes := Changes{
+		Upserted:       append(append([]akahu.TransactionResponse(nil), result.Added...), result.Updated...),
+		Pending:        pending,
+		ReplacePending: true,
+		HighWaterMark:  end,
+	}
+	if err := s.store.Apply(ctx, userID, changes); err != nil {
+		return nil, nil, err
+	}
+
+	return result, res, nil
+}
+
+// dedupe removes transactions with the same ID, keeping the most recently updated.
+func dedupe(transactions []akahu.TransactionResponse) []akahu.TransactionResponse {
+	index := map[akahu.TransactionID]int{}
+	deduped := make([]akahu.TransactionResponse, 0, len(transactions))
+
+	for _, t := range transactions {
+		i, ok := index[t.Id]
+		if !ok {
+			index[t.Id] = len(deduped)
+			deduped = append(deduped, t)
+			continue
+		}
+
+		if t.UpdatedAt.After(deduped[i].UpdatedAt) {
+			deduped[i] = t
+		}
+	}
+
+	return deduped
+}
@@ -0,0 +1,277 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+	"github.com/shopspring/decimal"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// fakeAkahu serves the settled and pending transactions endpoints, recording the query of each request. If pageSize
+// is set, settled transactions are served in pages of that size, and the page failPage fails.
+type fakeAkahu struct {
+	settled  []akahu.TransactionResponse
+	pending  []akahu.TransactionResponse
+	queries  []string
+	pageSize int
+	failPage int
+}
+
+func (f *fakeAkahu) client() *akahu.Client {
+	httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		f.queries = append(f.queries, r.URL.RawQuery)
+
+		items := f.settled
+		if strings.HasSuffix(r.URL.Path, "/pending") {
+			items = f.pending
+		}
+
+		response := map[string]interface{}{"success": true}
+		if f.pageSize > 0 && !strings.HasSuffix(r.URL.Path, "/pending") {
+			page, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Query().Get("cursor"), "page_"))
+			if f.failPage > 0 && page == f.failPage {
+				return &http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Body:       io.NopCloser(strings.NewReader(`{ "success": false, "message": "Internal error" }`)),
+				}, nil
+			}
+
+			start, end := page*f.pageSize, (page+1)*f.pageSize
+			if end < len(items) {
+				response["cursor"] = map[string]string{"next": fmt.Sprintf("page_%d", page+1)}
+			} else {
+				end = len(items)
+			}
+			items = items[start:end]
+		}
+		response["items"] = items
+		body, _ := json.Marshal(response)
+
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+	})}
+
+	return akahu.NewClient(httpClient, "app_token_123", "appSecret123", "")
+}
+
+func transaction(id akahu.TransactionID, date, updatedAt time.Time, amount string) akahu.TransactionResponse {
+	return akahu.TransactionResponse{
+		Id:          id,
+		Account:     "acc_1",
+		Date:        date,
+		UpdatedAt:   updatedAt,
+		Description: "Transaction " + string(id),
+		Amount:      decimal.RequireFromString(amount),
+	}
+}
+
+func transactionIDs(transactions []akahu.TransactionResponse) []string {
+	ids := make([]string, len(transactions))
+	for i, t := range transactions {
+		ids[i] = string(t.Id)
+	}
+
+	return ids
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) Store
+	}{
+		{
+			name: "with memory store",
+			store: func(t *testing.T) Store {
+				return NewMemoryStore()
+			},
+		},
+		{
+			name: "with file store",
+			store: func(t *testing.T) Store {
+				store, err := NewFileStore(t.TempDir())
+				if err != nil {
+					t.Fatalf("NewFileStore returned err %v", err)
+				}
+				return store
+			},
+		},
+	}
+
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	day3 := day2.Add(24 * time.Hour)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.TODO()
+			store := test.store(t)
+			api := &fakeAkahu{}
+
+			syncer := NewSyncer(api.client(), store)
+			syncer.now = func() time.Time { return day2 }
+
+			api.settled = []akahu.TransactionResponse{
+				transaction("trans_1", day1, day1, "-10.00"),
+				transaction("trans_2", day1, day1, "-20.00"),
+				transaction("trans_1", day1, day1, "-10.00"),
+			}
+			api.pending = []akahu.TransactionResponse{{Account: "acc_1", Date: day2, Description: "Pending", Amount: decimal.RequireFromString("-5.00")}}
+
+			result, res, err := syncer.Sync(ctx, "user_1", "user_token_1")
+			if err != nil || !res.Success {
+				t.Fatalf("Sync returned err %v, response %v", err, res)
+			}
+			if ids := transactionIDs(result.Added); !reflect.DeepEqual(ids, []string{"trans_1", "trans_2"}) {
+				t.Fatalf("expected added trans_1 and trans_2, actual %v", ids)
+			}
+			if expected := "end=2024-01-02T00%3A00%3A00Z&start=2023-10-04T00%3A00%3A00Z"; api.queries[0] != expected {
+				t.Fatalf("expected initial query %s, actual %s", expected, api.queries[0])
+			}
+
+			// The second sync overlaps the first, and sees one transaction updated, one new and the pending one settled.
+			syncer.now = func() time.Time { return day3 }
+			api.queries = nil
+			api.settled = []akahu.TransactionResponse{
+				transaction("trans_1", day1, day1, "-10.00"),
+				transaction("trans_2", day1, day2, "-25.00"),
+				transaction("trans_3", day2, day2, "-5.00"),
+			}
+			api.pending = nil
+
+			result, _, err = syncer.Sync(ctx, "user_1", "user_token_1")
+			if err != nil {
+				t.Fatalf("Sync returned err %v", err)
+			}
+			if ids := transactionIDs(result.Added); !reflect.DeepEqual(ids, []string{"trans_3"}) {
+				t.Fatalf("expected added trans_3, actual %v", ids)
+			}
+			if ids := transactionIDs(result.Updated); !reflect.DeepEqual(ids, []string{"trans_2"}) {
+				t.Fatalf("expected updated trans_2, actual %v", ids)
+			}
+			if len(result.Reconciliation.Dropped) != 1 {
+				t.Fatalf("expected the previous pending transaction to be dropped, actual %v", result.Reconciliation)
+			}
+			if result.Unchanged != 1 {
+				t.Fatalf("expected 1 unchanged, actual %d", result.Unchanged)
+			}
+			if expected := "end=2024-01-03T00%3A00%3A00Z&start=2023-12-26T00%3A00%3A00Z"; api.queries[0] != expected {
+				t.Fatalf("expected overlapping query %s, actual %s", expected, api.queries[0])
+			}
+
+			stored, _ := store.List(ctx, "user_1")
+			if ids := transactionIDs(stored); !reflect.DeepEqual(ids, []string{"trans_1", "trans_2", "trans_3"}) {
+				t.Fatalf("expected stored transactions trans_1, trans_2 and trans_3, actual %v", ids)
+			}
+			if !stored[1].Amount.Equal(decimal.RequireFromString("-25")) {
+				t.Fatalf("expected updated amount -25, actual %s", stored[1].Amount)
+			}
+
+			pending, _ := store.Pending(ctx, "user_1")
+			if len(pending) != 0 {
+				t.Fatalf("expected no pending transactions, actual %v", pending)
+			}
+
+			highWaterMark, _ := store.HighWaterMark(ctx, "user_1")
+			if !highWaterMark.Equal(day3) {
+				t.Fatalf("expected high-water mark %v, actual %v", day3, highWaterMark)
+			}
+		})
+	}
+}
+
+func TestSyncer_Sync_Unsuccessful(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Body:       io.NopCloser(strings.NewReader(`{ "success": false, "message": "Unauthorized" }`)),
+		}, nil
+	})}
+	store := NewMemoryStore()
+	syncer := NewSyncer(akahu.NewClient(httpClient, "app_token_123", "appSecret123", ""), store)
+
+	result, res, err := syncer.Sync(context.TODO(), "user_1", "user_token_1")
+	if err != nil || result != nil || res.Success {
+		t.Fatalf("expected unsuccessful response and no result, actual %v %v %v", result, res, err)
+	}
+
+	if highWaterMark, _ := store.HighWaterMark(context.TODO(), "user_1"); !highWaterMark.IsZero() {
+		t.Fatalf("expected high-water mark to be unchanged, actual %v", highWaterMark)
+	}
+}
+
+func TestSyncer_Sync_Pages(t *testing.T) {
+	ctx := context.TODO()
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	api := &fakeAkahu{pageSize: 2, failPage: 2}
+	for i := 1; i <= 5; i++ {
+		api.settled = append(api.settled, transaction(akahu.TransactionID(fmt.Sprintf("trans_%d", i)), day1, day1, "-10.00"))
+	}
+
+	syncer := NewSyncer(api.client(), store)
+	syncer.now = func() time.Time { return day1 }
+
+	// A failure part way through the pages saves nothing, so the next sync fetches the same range again.
+	result, res, err := syncer.Sync(ctx, "user_1", "user_token_1")
+	if err != nil || result != nil || res.Success {
+		t.Fatalf("expected unsuccessful response and no result, actual %v %v %v", result, res, err)
+	}
+	if highWaterMark, _ := store.HighWaterMark(ctx, "user_1"); !highWaterMark.IsZero() {
+		t.Fatalf("expected high-water mark to be unchanged, actual %v", highWaterMark)
+	}
+
+	api.failPage = 0
+	result, res, err = syncer.Sync(ctx, "user_1", "user_token_1")
+	if err != nil || !res.Success {
+		t.Fatalf("Sync returned err %v, response %v", err, res)
+	}
+	if ids := transactionIDs(result.Added); !reflect.DeepEqual(ids, []string{"trans_1", "trans_2", "trans_3", "trans_4", "trans_5"}) {
+		t.Fatalf("expected every page to be added, actual %v", ids)
+	}
+	if highWaterMark, _ := store.HighWaterMark(ctx, "user_1"); !highWaterMark.Equal(day1) {
+		t.Fatalf("expected high-water mark %v, actual %v", day1, highWaterMark)
+	}
+}
+
+func TestSyncer_Sync_ReconcilesStoredSettled(t *testing.T) {
+	ctx := context.TODO()
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	store := NewMemoryStore()
+	api := &fakeAkahu{}
+
+	syncer := NewSyncer(api.client(), store)
+
+	// The bank lists the transaction as both settled and pending for a couple of syncs.
+	settled := transaction("trans_1", day1, day1, "-10.00")
+	pending := settled
+	pending.Id = ""
+	api.settled = []akahu.TransactionResponse{settled}
+	api.pending = []akahu.TransactionResponse{pending}
+
+	for i, now := range []time.Time{day1, day2} {
+		syncer.now = func() time.Time { return now }
+
+		result, res, err := syncer.Sync(ctx, "user_1", "user_token_1")
+		if err != nil || !res.Success {
+			t.Fatalf("Sync returned err %v, response %v", err, res)
+		}
+		if len(result.Reconciliation.Matched) != 1 || len(result.Reconciliation.StillPending) != 0 {
+			t.Fatalf("expected sync %d to match the pending transaction, actual %+v", i, result.Reconciliation)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package sync
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// ErrUnknownUser is returned by WebhookSyncer.Handle when a TRANSACTION webhook has no state to identify the user by.
+var ErrUnknownUser = errors.New("sync: webhook has no state identifying the user")
+
+// WebhookSyncer keeps a Store up to date by applying TRANSACTION webhooks, rather than polling with a Syncer.
+// Payloads must be verified with akahu.ValidateWebhookSignature before they are handled.
+type WebhookSyncer struct {
+	client *akahu.Client
+	store  Store
+
+	// Feed publishes the changes made by each webhook.
+	Feed *Feed
+}
+
+// NewWebhookSyncer creates a WebhookSyncer that fetches transactions with client and saves them to store.
+func NewWebhookSyncer(client *akahu.Client, store Store) *WebhookSyncer {
+	return &WebhookSyncer{
+		client: client,
+		store:  store,
+		Feed:   &Feed{},
+	}
+}
+
+// Handle applies a TRANSACTION webhook for a user whose access token is held in the client's Tokens. The user is
+// identified by the webhook's state, which UserClient.Webhooks.Subscribe sets to the user ID. Other types of webhook
+// are ignored.
+func (s *WebhookSyncer) Handle(ctx context.Context, payload akahu.WebHookEventPayload) ([]Change, *akahu.APIResponse, error) {
+	if payload.WebhookType != akahu.Transaction {
+		return nil, nil, nil
+	}
+	if payload.State == "" {
+		return nil, nil, ErrUnknownUser
+	}
+
+	userAccessToken, err := s.client.Tokens.Get(ctx, payload.State)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.Apply(ctx, payload.State, userAccessToken, payload)
+}
+
+// Apply applies a TRANSACTION webhook for the user. New transactions are fetched with GetByIds and saved, and
+// removed transactions are deleted from the store. The changes are returned, and published to the Feed once they are
+// saved.
+//
+// If Akahu returns an unsuccessful response nothing is saved, and the response is returned with no changes.
+func (s *WebhookSyncer) Apply(ctx context.Context, userID, userAccessToken string, payload akahu.WebHookEventPayload) ([]Change, *akahu.APIResponse, error) {
+	if payload.WebhookType != akahu.Transaction {
+		return nil, nil, nil
+	}
+
+	var (
+		changes []Change
+		res     *akahu.APIResponse
+		err     error
+	)
+	switch payload.WebhookCode {
+	case akahu.TransactionInitialUpdate, akahu.TransactionDefaultUpdate:
+		changes, res, err = s.applyNew(ctx, userID, userAccessToken, payload.NewTransactionIds)
+	case akahu.TransactionDelete:
+		changes, err = s.applyRemoved(ctx, userID, payload.RemovedTransactions)
+	default:
+		return nil, nil, nil
+	}
+	if err != nil || len(changes) == 0 {
+		return nil, res, err
+	}
+
+	// The changes are saved, so they are published without failing the webhook, which would make Akahu retry it.
+	s.Feed.publish(changes)
+	return changes, res, nil
+}
+
+func (s *WebhookSyncer) applyNew(ctx context.Context, userID, userAccessToken string, ids []akahu.TransactionID) ([]Change, *akahu.APIResponse, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	// GetByIds splits large lists of ids into batches within Akahu's limit.
+	fetched, res, err := s.client.Transactions.GetByIds(ctx, userAccessToken, ids...)
+	if err != nil || !res.Success {
+		return nil, res, err
+	}
+
+	fetchedIDs := make([]akahu.TransactionID, len(fetched))
+	for i, t := range fetched {
+		fetchedIDs[i] = t.Id
+	}
+
+	stored, err := s.store.Transactions(ctx, userID, fetchedIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changes []Change
+	var upserted []akahu.TransactionResponse
+	for _, t := range fetched {
+		existing, ok := stored[t.Id]
+		switch {
+		case !ok:
+			changes = append(changes, Change{UserID: userID, Type: ChangeAdded, Transaction: t})
+		case t.UpdatedAt.After(existing.UpdatedAt):
+			changes = append(changes, Change{UserID: userID, Type: ChangeUpdated, Transaction: t})
+		default:
+			continue
+		}
+		upserted = append(upserted, t)
+	}
+
+	if len(upserted) > 0 {
+		if err := s.store.Apply(ctx, userID, Changes{Upserted: upserted}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return changes, res, nil
+}
+
+func (s *WebhookSyncer) applyRemoved(ctx context.Context, userID string, ids []akahu.TransactionID) ([]Change, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	stored, err := s.store.Transactions(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Apply(ctx, userID, Changes{Deleted: ids}); err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, len(ids))
+	for i, id := range ids {
+		t, ok := stored[id]
+		if !ok {
+			t = akahu.TransactionResponse{Id: id}
+		}
+		changes[i] = Change{UserID: userID, Type: ChangeRemoved, Transaction: t}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	stdsync "sync"
+	"testing"
+	"time"
+
+	"github.com/jdebes/akahu-sdk-go/akahu"
+)
+
+// getByIdsClient serves transactions/ids from transactions, recording the number of IDs in each request.
+func getByIdsClient(transactions map[akahu.TransactionID]akahu.TransactionResponse, batches *[]int) *akahu.Client {
+	var mu stdsync.Mutex
+	httpClient := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var ids []akahu.TransactionID
+		_ = json.NewDecoder(r.Body).Decode(&ids)
+
+		mu.Lock()
+		defer mu.Unlock()
+		*batches = append(*batches, len(ids))
+
+		items := []akahu.TransactionResponse{}
+		for _, id := range ids {
+			if t, ok := transactions[id]; ok {
+				items = append(items, t)
+			}
+		}
+		body, _ := json.Marshal(map[string]interface{}{"success": true, "items": items})
+
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+	})}
+
+	return akahu.NewClient(httpClient, "app_token_123", "appSecret123", "")
+}
+
+func TestWebhookSyncer(t *testing.T) {
+	ctx := context.TODO()
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	transactions := map[akahu.TransactionID]akahu.TransactionResponse{}
+	var ids []akahu.TransactionID
+	for i := 0; i < 150; i++ {
+		id := akahu.TransactionID(fmt.Sprintf("trans_%03d", i))
+		ids = append(ids, id)
+		transactions[id] = transaction(id, day1, day1, "-1.00")
+	}
+
+	var batches []int
+	client := getByIdsClient(transactions, &batches)
+	_ = client.Tokens.Put(ctx, "user_1", "user_token_1")
+
+	store := NewMemoryStore()
+	syncer := NewWebhookSyncer(client, store)
+
+	feed, unsubscribe := syncer.Feed.Subscribe(200)
+	defer unsubscribe()
+
+	changes, _, err := syncer.Handle(ctx, akahu.WebHookEventPayload{
+		WebhookType:       akahu.Transaction,
+		WebhookCode:       akahu.TransactionInitialUpdate,
+		State:             "user_1",
+		NewTransactions:   len(ids),
+		NewTransactionIds: ids,
+	})
+	if err != nil {
+		t.Fatalf("Handle returned err %v", err)
+	}
+	if len(changes) != 150 || changes[0].Type != ChangeAdded || changes[0].UserID != "user_1" {
+		t.Fatalf("expected 150 added changes, actual %d %v", len(changes), changes[0])
+	}
+	sort.Ints(batches)
+	if len(batches) != 2 || batches[0] != 50 || batches[1] != 100 {
+		t.Fatalf("expected GetByIds batches of 100 and 50, actual %v", batches)
+	}
+	if len(feed) != 150 {
+		t.Fatalf("expected 150 changes in feed, actual %d", len(feed))
+	}
+
+	// An update to a transaction that is already stored, and one with no changes.
+	updated := transaction("trans_000", day1, day1.Add(time.Hour), "-2.00")
+	transactions["trans_000"] = updated
+	changes, _, _ = syncer.Handle(ctx, akahu.WebHookEventPayload{
+		WebhookType:       akahu.Transaction,
+		WebhookCode:       akahu.TransactionDefaultUpdate,
+		State:             "user_1",
+		NewTransactionIds: []akahu.TransactionID{"trans_000", "trans_001"},
+	})
+	if len(changes) != 1 || changes[0].Type != ChangeUpdated || changes[0].Transaction.Id != "trans_000" {
+		t.Fatalf("expected trans_000 to be updated, actual %v", changes)
+	}
+
+	changes, _, _ = syncer.Handle(ctx, akahu.WebHookEventPayload{
+		WebhookType:         akahu.Transaction,
+		WebhookCode:         akahu.TransactionDelete,
+		State:               "user_1",
+		RemovedTransactions: []akahu.TransactionID{"trans_000", "trans_unknown"},
+	})
+	if len(changes) != 2 || changes[0].Type != ChangeRemoved || !changes[0].Transaction.Amount.Equal(updated.Amount) || changes[1].Transaction.Id != "trans_unknown" {
+		t.Fatalf("expected trans_000 and trans_unknown to be removed, actual %v", changes)
+	}
+
+	stored, _ := store.List(ctx, "user_1")
+	if len(stored) != 149 || stored[0].Id != "trans_001" {
+		t.Fatalf("expected 149 stored transactions from trans_001, actual %d", len(stored))
+	}
+
+	if _, _, err := syncer.Handle(ctx, akahu.WebHookEventPayload{WebhookType: akahu.Transaction, WebhookCode: akahu.TransactionDelete}); err != ErrUnknownUser {
+		t.Fatalf("expected err %v, actual %v", ErrUnknownUser, err)
+	}
+}